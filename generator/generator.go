@@ -0,0 +1,532 @@
+// Copyright 2022 CJ Harries
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/wizardsoftheweb/terragrunt-builder/parser"
+)
+
+const (
+	// DefaultRequiredPlaceholder is the value emitted for required variables when no placeholder is configured
+	DefaultRequiredPlaceholder = "TODO"
+	// SensitiveWarning is the comment emitted above every sensitive input
+	SensitiveWarning = "SENSITIVE: do not hardcode"
+)
+
+// RenderOptions tweaks how Terragrunt configuration is generated. The zero value is ready to use.
+type RenderOptions struct {
+	// RequiredPlaceholder is the string value emitted for variables without a default. Defaults to
+	// DefaultRequiredPlaceholder.
+	RequiredPlaceholder string
+	// GroupBySourceFile groups inputs by the file their variable was declared in, each group introduced by a
+	// "# from: <file>" banner. Groups appear in the order their first variable does.
+	GroupBySourceFile bool
+	// InputsFromLocals moves every value into a generated locals block and points each input at its local, so values
+	// are overridden in one place. Locals are named after their variable unless that collides with a module local.
+	InputsFromLocals bool
+	// ChangedSince limits generation to variables added or changed since a baseline, e.g. one from
+	// parser.ParseGitRef. Nil generates every variable.
+	ChangedSince *parser.Terraform
+}
+
+// requiredPlaceholder resolves the placeholder for required variables
+func (opts RenderOptions) requiredPlaceholder() string {
+	if "" == opts.RequiredPlaceholder {
+		return DefaultRequiredPlaceholder
+	}
+	return opts.RequiredPlaceholder
+}
+
+// newlineTokens is a single newline, used to end each entry in a generated object
+func newlineTokens() hclwrite.Tokens {
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+}
+
+// commentTokens builds a single line comment. HCL comment tokens carry their own trailing newline.
+func commentTokens(comment string) hclwrite.Tokens {
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenComment, Bytes: []byte(fmt.Sprintf("# %s\n", comment))},
+	}
+}
+
+// requiredComment describes a required variable in a single line
+func requiredComment(variable *parser.Variable) string {
+	if "" == variable.Description {
+		return "required"
+	}
+	return fmt.Sprintf("required: %s", strings.Join(strings.Fields(variable.Description), " "))
+}
+
+// defaultExpressionComment describes a default that couldn't be evaluated in a single line
+func defaultExpressionComment(variable *parser.Variable) string {
+	return fmt.Sprintf("default: %s", strings.Join(strings.Fields(variable.DefaultExpression), " "))
+}
+
+// inputTokens builds the entry holding a single variable's value under the given name. Sensitive variables are always
+// preceded by a warning.
+func inputTokens(name string, variable *parser.Variable, opts RenderOptions) (hclwrite.Tokens, error) {
+	var tokens hclwrite.Tokens
+	if variable.Sensitive {
+		tokens = append(tokens, commentTokens(SensitiveWarning)...)
+	}
+	tokens = append(tokens, hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+		{Type: hclsyntax.TokenEqual, Bytes: []byte("=")},
+	}...)
+	if !variable.HasDefault {
+		tokens = append(tokens, hclwrite.TokensForValue(cty.StringVal(opts.requiredPlaceholder()))...)
+		return append(tokens, commentTokens(requiredComment(variable))...), nil
+	}
+	// The module's default needs its locals to evaluate, so it's noted for whoever fills in the placeholder
+	if "" != variable.DefaultExpression {
+		tokens = append(tokens, hclwrite.TokensForValue(cty.StringVal(opts.requiredPlaceholder()))...)
+		return append(tokens, commentTokens(defaultExpressionComment(variable))...), nil
+	}
+	if !variable.Default.IsWhollyKnown() {
+		return nil, fmt.Errorf("default for variable %s is not known", variable.Name)
+	}
+	tokens = append(tokens, hclwrite.TokensForValue(variable.Default)...)
+	return append(tokens, newlineTokens()...), nil
+}
+
+// referenceTokens builds an entry whose value is the given traversal
+func referenceTokens(name string, traversal hcl.Traversal) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+		{Type: hclsyntax.TokenEqual, Bytes: []byte("=")},
+	}
+	tokens = append(tokens, hclwrite.TokensForTraversal(traversal)...)
+	return append(tokens, newlineTokens()...)
+}
+
+// localReferenceTokens builds an input entry pointing at the local holding its value
+func localReferenceTokens(name string, localName string) hclwrite.Tokens {
+	return referenceTokens(name, hcl.Traversal{
+		hcl.TraverseRoot{Name: "local"},
+		hcl.TraverseAttr{Name: localName},
+	})
+}
+
+// dependencyReferenceTokens builds an input entry passing through a dependency's output of the same name
+func dependencyReferenceTokens(depName string, outputName string) hclwrite.Tokens {
+	return referenceTokens(outputName, hcl.Traversal{
+		hcl.TraverseRoot{Name: "dependency"},
+		hcl.TraverseAttr{Name: depName},
+		hcl.TraverseAttr{Name: "outputs"},
+		hcl.TraverseAttr{Name: outputName},
+	})
+}
+
+// objectTokens wraps entries in braces so they can be the value of an attribute
+func objectTokens(entryTokens hclwrite.Tokens) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")},
+	}
+	tokens = append(tokens, newlineTokens()...)
+	tokens = append(tokens, entryTokens...)
+	return append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte("}")})
+}
+
+// localNames picks the local holding each variable's value. Names that collide with a module local, or an earlier
+// pick, get "_input" appended until they're unique.
+func localNames(t parser.Terraform) map[*parser.Variable]string {
+	used := map[string]bool{}
+	for name := range t.Locals {
+		used[name] = true
+	}
+	names := map[*parser.Variable]string{}
+	for _, variable := range t.Variables {
+		name := variable.Name
+		for used[name] {
+			name += "_input"
+		}
+		used[name] = true
+		names[variable] = name
+	}
+	return names
+}
+
+// variableChanged reports whether a variable differs from its baseline declaration in anything that affects its input
+func variableChanged(variable *parser.Variable, baseline *parser.Variable) bool {
+	if variable.Type != baseline.Type || variable.Description != baseline.Description ||
+		variable.Sensitive != baseline.Sensitive || variable.HasDefault != baseline.HasDefault ||
+		variable.DefaultExpression != baseline.DefaultExpression {
+		return true
+	}
+	return variable.HasDefault && !variable.Default.RawEquals(baseline.Default)
+}
+
+// changedVariables keeps the variables added or changed since the baseline, or every variable without one
+func changedVariables(variables []*parser.Variable, baseline *parser.Terraform) []*parser.Variable {
+	if nil == baseline {
+		return variables
+	}
+	baselineVariables := map[string]*parser.Variable{}
+	for _, variable := range baseline.Variables {
+		baselineVariables[variable.Name] = variable
+	}
+	var changed []*parser.Variable
+	for _, variable := range variables {
+		baselineVariable, existed := baselineVariables[variable.Name]
+		if !existed || variableChanged(variable, baselineVariable) {
+			changed = append(changed, variable)
+		}
+	}
+	return changed
+}
+
+// groupBySourceFile splits variables by the file they were declared in, preserving order within and across groups
+func groupBySourceFile(variables []*parser.Variable) (groups [][]*parser.Variable) {
+	indices := map[string]int{}
+	for _, variable := range variables {
+		index, seen := indices[variable.Position.Filename]
+		if !seen {
+			index = len(groups)
+			indices[variable.Position.Filename] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], variable)
+	}
+	return groups
+}
+
+// GenerateInputs builds a Terragrunt inputs block using the default RenderOptions
+func GenerateInputs(t parser.Terraform) (string, error) {
+	return GenerateInputsWithOptions(t, RenderOptions{})
+}
+
+// eachInput calls fn with the tokens entry builds for each variable in order. Group banners are attached to the first
+// entry of their group.
+func eachInput(t parser.Terraform, opts RenderOptions, entry func(*parser.Variable) (hclwrite.Tokens, error), fn func(hclwrite.Tokens) error) error {
+	variables := changedVariables(t.Variables, opts.ChangedSince)
+	groups := [][]*parser.Variable{variables}
+	if opts.GroupBySourceFile {
+		groups = groupBySourceFile(variables)
+	}
+	for index, group := range groups {
+		var bannerTokens hclwrite.Tokens
+		if opts.GroupBySourceFile {
+			if 0 < index {
+				bannerTokens = append(bannerTokens, newlineTokens()...)
+			}
+			bannerTokens = append(bannerTokens, commentTokens(fmt.Sprintf("from: %s", filepath.Base(group[0].Position.Filename)))...)
+		}
+		for _, variable := range group {
+			tokens, err := entry(variable)
+			if nil != err {
+				return err
+			}
+			if err = fn(append(bannerTokens, tokens...)); nil != err {
+				return err
+			}
+			bannerTokens = nil
+		}
+	}
+	return nil
+}
+
+// generatedBlock is one top-level block of generated configuration, either an attribute holding an object or a block
+type generatedBlock struct {
+	name        string
+	isAttribute bool
+	entry       func(*parser.Variable) (hclwrite.Tokens, error)
+}
+
+// header opens the block
+func (block generatedBlock) header() string {
+	if block.isAttribute {
+		return fmt.Sprintf("%s = {\n", block.name)
+	}
+	return fmt.Sprintf("%s {\n", block.name)
+}
+
+// generatedBlocks lays out the blocks rendered for the options. Inputs hold the values unless they come from locals.
+func generatedBlocks(t parser.Terraform, opts RenderOptions) []generatedBlock {
+	if !opts.InputsFromLocals {
+		return []generatedBlock{
+			{
+				name:        "inputs",
+				isAttribute: true,
+				entry: func(variable *parser.Variable) (hclwrite.Tokens, error) {
+					return inputTokens(variable.Name, variable, opts)
+				},
+			},
+		}
+	}
+	names := localNames(t)
+	return []generatedBlock{
+		{
+			name: "locals",
+			entry: func(variable *parser.Variable) (hclwrite.Tokens, error) {
+				return inputTokens(names[variable], variable, opts)
+			},
+		},
+		{
+			name:        "inputs",
+			isAttribute: true,
+			entry: func(variable *parser.Variable) (hclwrite.Tokens, error) {
+				return localReferenceTokens(variable.Name, names[variable]), nil
+			},
+		},
+	}
+}
+
+// GenerateInputsWithOptions builds a Terragrunt inputs block with one entry per variable, keyed by name and valued with
+// the variable's default. Variables without a default are required, so they get a placeholder value and a comment. With
+// InputsFromLocals the values move to a locals block rendered first.
+func GenerateInputsWithOptions(t parser.Terraform, opts RenderOptions) (string, error) {
+	file := hclwrite.NewEmptyFile()
+	for index, block := range generatedBlocks(t, opts) {
+		var entryTokens hclwrite.Tokens
+		err := eachInput(t, opts, block.entry, func(tokens hclwrite.Tokens) error {
+			entryTokens = append(entryTokens, tokens...)
+			return nil
+		})
+		if nil != err {
+			return "", err
+		}
+		if 0 < index {
+			file.Body().AppendNewline()
+		}
+		if !block.isAttribute {
+			file.Body().AppendNewBlock(block.name, nil).Body().AppendUnstructuredTokens(entryTokens)
+			continue
+		}
+		file.Body().SetAttributeRaw(block.name, objectTokens(entryTokens))
+	}
+	return string(hclwrite.Format(file.Bytes())), nil
+}
+
+// startsAlignmentBreak reports whether an entry's first line stops the formatter aligning it with the lines above.
+// Blank lines, comment lines, and lines opening a multi-line value all do.
+func startsAlignmentBreak(tokens hclwrite.Tokens) bool {
+	if 0 == len(tokens) || hclsyntax.TokenNewline == tokens[0].Type || hclsyntax.TokenComment == tokens[0].Type {
+		return true
+	}
+	depth := 0
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenOBrace, hclsyntax.TokenOBrack, hclsyntax.TokenOParen:
+			depth++
+		case hclsyntax.TokenCBrace, hclsyntax.TokenCBrack, hclsyntax.TokenCParen:
+			depth--
+		case hclsyntax.TokenNewline, hclsyntax.TokenComment:
+			return 0 < depth
+		}
+	}
+	return 0 < depth
+}
+
+// entryLayout is what streamBlock keeps about an entry between its two passes. Only an entry ending in a single
+// key = value line is aligned with its neighbours, so only that line is measured.
+type entryLayout struct {
+	breaksAlignment bool
+	aligned         bool
+	commented       bool
+	keyWidth        int
+	assignWidth     int
+	keyColumn       int
+	commentColumn   int
+}
+
+// formatEntry formats a single entry as it would be formatted inside the block opened by header
+func formatEntry(header string, tokens hclwrite.Tokens) []byte {
+	const footer = "}\n"
+	// Wrapping the entry keeps its indentation identical to the full block
+	source := append(append([]byte(header), tokens.Bytes()...), footer...)
+	formatted := hclwrite.Format(source)
+	return formatted[bytes.IndexByte(formatted, '\n')+1 : len(formatted)-len(footer)]
+}
+
+// splitLastLine splits a formatted entry into the lines before its last and the last line without its newline
+func splitLastLine(formatted []byte) ([]byte, []byte) {
+	trimmed := formatted[:len(formatted)-1]
+	start := bytes.LastIndexByte(trimmed, '\n') + 1
+	return formatted[:start], trimmed[start:]
+}
+
+// endsAligned reports whether an entry ends in a single key = value line, and whether that line has a trailing comment
+func endsAligned(tokens hclwrite.Tokens) (aligned bool, commented bool) {
+	last := len(tokens) - 1
+	start := last
+	for 0 < start && hclsyntax.TokenNewline != tokens[start-1].Type && hclsyntax.TokenComment != tokens[start-1].Type {
+		start--
+	}
+	if start+1 >= last || hclsyntax.TokenEqual != tokens[start+1].Type {
+		return false, false
+	}
+	return true, hclsyntax.TokenComment == tokens[last].Type
+}
+
+// measureEntry lays out an entry on its own, ready to be aligned with its neighbours
+func measureEntry(header string, tokens hclwrite.Tokens) entryLayout {
+	layout := entryLayout{breaksAlignment: startsAlignmentBreak(tokens)}
+	layout.aligned, layout.commented = endsAligned(tokens)
+	if !layout.aligned {
+		return layout
+	}
+	_, line := splitLastLine(formatEntry(header, tokens))
+	if layout.commented {
+		line = line[:len(line)-len(bytes.TrimRight(tokens[len(tokens)-1].Bytes, "\n"))-1]
+	}
+	layout.keyWidth = bytes.Index(line, []byte(" = "))
+	layout.assignWidth = len(line) - layout.keyWidth - 1
+	return layout
+}
+
+// alignLayouts picks the columns the formatter would give each entry's = and trailing comment. Like the formatter, it
+// aligns = across consecutive key = value lines, then trailing comments across consecutive commented lines.
+func alignLayouts(layouts []entryLayout) {
+	for start := 0; start < len(layouts); {
+		end := start + 1
+		for end < len(layouts) && layouts[end-1].aligned && !layouts[end].breaksAlignment {
+			end++
+		}
+		keyColumn := 0
+		for index := start; index < end; index++ {
+			if layouts[index].keyWidth > keyColumn {
+				keyColumn = layouts[index].keyWidth
+			}
+		}
+		for commentStart := start; commentStart < end; {
+			commentEnd := commentStart
+			commentColumn := 0
+			for ; commentEnd < end && layouts[commentEnd].commented; commentEnd++ {
+				if width := keyColumn + 1 + layouts[commentEnd].assignWidth; width > commentColumn {
+					commentColumn = width
+				}
+			}
+			for index := commentStart; index < commentEnd; index++ {
+				layouts[index].commentColumn = commentColumn
+			}
+			commentStart = commentEnd + 1
+		}
+		for index := start; index < end; index++ {
+			layouts[index].keyColumn = keyColumn
+		}
+		start = end
+	}
+}
+
+// alignedEntry formats an entry and pads its last line to the columns picked for it
+func alignedEntry(header string, tokens hclwrite.Tokens, layout entryLayout) []byte {
+	formatted := formatEntry(header, tokens)
+	if !layout.aligned {
+		return formatted
+	}
+	previousLines, line := splitLastLine(formatted)
+	var comment []byte
+	if layout.commented {
+		comment = bytes.TrimRight(tokens[len(tokens)-1].Bytes, "\n")
+		line = line[:len(line)-len(comment)-1]
+	}
+	entry := append([]byte{}, previousLines...)
+	entry = append(entry, line[:layout.keyWidth]...)
+	entry = append(entry, bytes.Repeat([]byte(" "), layout.keyColumn-layout.keyWidth+1)...)
+	entry = append(entry, line[layout.keyWidth+1:]...)
+	if layout.commented {
+		entry = append(entry, bytes.Repeat([]byte(" "), layout.commentColumn-layout.keyColumn-layout.assignWidth)...)
+		entry = append(entry, comment...)
+	}
+	return append(entry, '\n')
+}
+
+// streamBlock writes a single generated block to w one entry at a time. Alignment depends on the entries that follow,
+// so a first pass measures every entry and keeps only its widths, and a second renders each entry again to write it.
+func streamBlock(w io.Writer, t parser.Terraform, opts RenderOptions, block generatedBlock) error {
+	header := block.header()
+	var layouts []entryLayout
+	err := eachInput(t, opts, block.entry, func(tokens hclwrite.Tokens) error {
+		layouts = append(layouts, measureEntry(header, tokens))
+		return nil
+	})
+	if nil != err {
+		return err
+	}
+	alignLayouts(layouts)
+	if _, err = io.WriteString(w, header); nil != err {
+		return err
+	}
+	index := 0
+	err = eachInput(t, opts, block.entry, func(tokens hclwrite.Tokens) error {
+		_, err := w.Write(alignedEntry(header, tokens, layouts[index]))
+		index++
+		return err
+	})
+	if nil != err {
+		return err
+	}
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// GenerateInputsTo writes the same configuration as GenerateInputsWithOptions to w without building it in memory. Each
+// entry is formatted and written on its own, padded to the columns the formatter would align it to, so the output
+// matches exactly. Output written before an error is not retracted.
+func GenerateInputsTo(w io.Writer, t parser.Terraform, opts RenderOptions) error {
+	for index, block := range generatedBlocks(t, opts) {
+		if 0 < index {
+			if _, err := io.WriteString(w, "\n"); nil != err {
+				return err
+			}
+		}
+		if err := streamBlock(w, t, opts, block); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateDependencies builds a Terragrunt dependency block named depName whose config_path is the sibling directory of
+// the same name, followed by an inputs stub passing each output through as dependency.<depName>.outputs.<output>.
+// Outputs without a name are skipped.
+func GenerateDependencies(t parser.Terraform, depName string) (string, error) {
+	if "" == strings.TrimSpace(depName) {
+		return "", errors.New("dependency name cannot be blank")
+	}
+	if !hclsyntax.ValidIdentifier(depName) {
+		return "", fmt.Errorf("dependency name %s is not a valid identifier", depName)
+	}
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+	dependencyBody := body.AppendNewBlock("dependency", []string{depName}).Body()
+	dependencyBody.SetAttributeValue("config_path", cty.StringVal(fmt.Sprintf("../%s", depName)))
+	var entryTokens hclwrite.Tokens
+	for _, output := range t.Outputs {
+		if "" == output.Name {
+			continue
+		}
+		entryTokens = append(entryTokens, dependencyReferenceTokens(depName, output.Name)...)
+	}
+	if nil != entryTokens {
+		body.AppendNewline()
+		body.SetAttributeRaw("inputs", objectTokens(entryTokens))
+	}
+	return string(hclwrite.Format(file.Bytes())), nil
+}
@@ -0,0 +1,497 @@
+// Copyright 2022 CJ Harries
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/wizardsoftheweb/terragrunt-builder/parser"
+)
+
+const (
+	// fixtureDirectory is the directory containing the fixtures
+	fixtureDirectory = "test_fixtures"
+	// fixtureDirectoryGrouped is a directory whose variables are spread across two files
+	fixtureDirectoryGrouped = "grouped"
+	// fixtureFileCombined is a file containing variables of every kind of type
+	fixtureFileCombined = "combined.tf"
+	// fixtureFileCombinedSchema is the JSON Schema expected for fixtureFileCombined
+	fixtureFileCombinedSchema = "combined.schema.json"
+	// fixtureFileConditionalDefaults is a file containing conditional defaults over literals and over locals
+	fixtureFileConditionalDefaults = "conditional_defaults.tf"
+	// fixtureFileInputs is a file containing variables with and without defaults
+	fixtureFileInputs = "inputs.tf"
+	// fixtureFileSensitive is a file containing sensitive variables with and without defaults
+	fixtureFileSensitive = "sensitive.tf"
+	// fixtureFileLocals is a file whose module locals collide with a variable name
+	fixtureFileLocals = "locals.tf"
+	// fixtureFileOptionalAttributes is a file containing a variable whose object type has optional attributes
+	fixtureFileOptionalAttributes = "optional_attributes.tf"
+	// fixtureFileOutputs is a file containing only outputs
+	fixtureFileOutputs = "outputs.tf"
+)
+
+type GeneratorTestSuite struct {
+	suite.Suite
+	fixtureDirectory string
+}
+
+func (suite *GeneratorTestSuite) SetupSuite() {
+	suite.fixtureDirectory = path.Join(".", fixtureDirectory)
+}
+
+func TestGeneratorTestSuite(t *testing.T) {
+	suite.Run(t, new(GeneratorTestSuite))
+}
+
+// parseFixture parses a fixture file, failing the test if it can't
+func (suite *GeneratorTestSuite) parseFixture(fileName string) parser.Terraform {
+	terraform, err := parser.ParseFile(path.Join(suite.fixtureDirectory, fileName))
+	suite.Require().Nilf(err, "Fixture %s should parse", fileName)
+	return terraform
+}
+
+// parseGenerated parses generated HCL and returns its attributes, failing the test if it doesn't parse
+func (suite *GeneratorTestSuite) parseGenerated(generated string) hcl.Attributes {
+	file, diags := hclsyntax.ParseConfig([]byte(generated), "generated.hcl", hcl.Pos{Line: 1, Column: 1})
+	suite.Require().Falsef(diags.HasErrors(), "Generated HCL should parse: %s", diags)
+	attributes, diags := file.Body.JustAttributes()
+	suite.Require().Falsef(diags.HasErrors(), "Generated HCL should only contain attributes: %s", diags)
+	return attributes
+}
+
+// sortedKeys lists the names of attributes in order
+func sortedKeys(attributes hclsyntax.Attributes) []string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseGeneratedBlocks parses generated HCL and returns its blocks, failing the test if it doesn't parse
+func (suite *GeneratorTestSuite) parseGeneratedBlocks(generated string) hclsyntax.Blocks {
+	file, diags := hclsyntax.ParseConfig([]byte(generated), "generated.hcl", hcl.Pos{Line: 1, Column: 1})
+	suite.Require().Falsef(diags.HasErrors(), "Generated HCL should parse: %s", diags)
+	return file.Body.(*hclsyntax.Body).Blocks
+}
+
+// parseDependencies parses generated dependency HCL, checking the dependency block, and returns the traversal each
+// input refers to keyed by input name
+func (suite *GeneratorTestSuite) parseDependencies(generated string, depName string) map[string]string {
+	file, diags := hclsyntax.ParseConfig([]byte(generated), "generated.hcl", hcl.Pos{Line: 1, Column: 1})
+	suite.Require().Falsef(diags.HasErrors(), "Generated HCL should parse: %s", diags)
+	body := file.Body.(*hclsyntax.Body)
+	suite.Require().Lenf(body.Blocks, 1, "There should be a single block")
+	suite.Equalf("dependency", body.Blocks[0].Type, "Block should be a dependency")
+	suite.Equalf([]string{depName}, body.Blocks[0].Labels, "Block should be named after the dependency")
+	configPath, diags := body.Blocks[0].Body.Attributes["config_path"].Expr.Value(nil)
+	suite.Require().Falsef(diags.HasErrors(), "config_path should evaluate: %s", diags)
+	suite.Equalf(cty.StringVal("../"+depName), configPath, "config_path should point at the sibling directory")
+	references := map[string]string{}
+	inputs, ok := body.Attributes["inputs"]
+	if !ok {
+		return references
+	}
+	object, ok := inputs.Expr.(*hclsyntax.ObjectConsExpr)
+	suite.Require().Truef(ok, "Inputs should be an object")
+	for _, item := range object.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		suite.Require().Falsef(diags.HasErrors(), "Input names should evaluate: %s", diags)
+		traversal, diags := hcl.AbsTraversalForExpr(item.ValueExpr)
+		suite.Require().Falsef(diags.HasErrors(), "Inputs should be references: %s", diags)
+		steps := []string{traversal.RootName()}
+		for _, step := range traversal[1:] {
+			steps = append(steps, step.(hcl.TraverseAttr).Name)
+		}
+		references[key.AsString()] = strings.Join(steps, ".")
+	}
+	return references
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputs_Success() {
+	generated, err := GenerateInputs(suite.parseFixture(fixtureFileInputs))
+	suite.Nilf(err, "Error should be nil")
+	inputs, diags := suite.parseGenerated(generated)["inputs"].Expr.Value(nil)
+	suite.Falsef(diags.HasErrors(), "Inputs should evaluate")
+	expected := cty.ObjectVal(map[string]cty.Value{
+		"region": cty.StringVal("us-east-1"),
+		"zones":  cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		"tags":   cty.ObjectVal(map[string]cty.Value{"team": cty.StringVal("platform")}),
+		"vpc_id": cty.StringVal(DefaultRequiredPlaceholder),
+	})
+	suite.Truef(expected.RawEquals(inputs), "Inputs should be %#v", expected)
+	suite.Containsf(generated, `vpc_id = "TODO" # required: The VPC to deploy into`, "Required variables should get a placeholder")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsWithOptions_RequiredPlaceholder() {
+	opts := RenderOptions{RequiredPlaceholder: "FILL_ME_IN"}
+	generated, err := GenerateInputsWithOptions(suite.parseFixture(fixtureFileInputs), opts)
+	suite.Nilf(err, "Error should be nil")
+	suite.Containsf(generated, `vpc_id = "FILL_ME_IN" # required: The VPC to deploy into`, "Required variables should use the placeholder")
+	suite.Containsf(generated, `region = "us-east-1"`, "Optional variables should keep their defaults")
+	suite.parseGenerated(generated)
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsWithOptions_GroupBySourceFile() {
+	terraform, err := parser.Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryGrouped))
+	suite.Require().Nilf(err, "Fixture should parse")
+	generated, err := GenerateInputsWithOptions(terraform, RenderOptions{GroupBySourceFile: true})
+	suite.Nilf(err, "Error should be nil")
+	expected := `inputs = {
+  # from: compute.tf
+  ami           = "ami-123"
+  instance_type = "t3.micro"
+
+  # from: network.tf
+  cidr     = "10.0.0.0/16"
+  vpc_name = "main"
+}
+`
+	suite.Equalf(expected, generated, "Inputs should be grouped by file")
+	suite.parseGenerated(generated)
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputs_SensitiveWarning() {
+	generated, err := GenerateInputs(suite.parseFixture(fixtureFileSensitive))
+	suite.Nilf(err, "Error should be nil")
+	suite.Containsf(generated, "# SENSITIVE: do not hardcode\n  password = \"TODO\"", "Required sensitive inputs should be warned about")
+	suite.Containsf(generated, "# SENSITIVE: do not hardcode\n  api_token = \"changeme\"", "Sensitive inputs with defaults should be warned about")
+	suite.Equalf(2, strings.Count(generated, SensitiveWarning), "Only sensitive inputs should be warned about")
+	suite.Containsf(suite.parseGenerated(generated), "inputs", "Inputs should still parse")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsWithOptions_InputsFromLocals() {
+	generated, err := GenerateInputsWithOptions(suite.parseFixture(fixtureFileLocals), RenderOptions{InputsFromLocals: true})
+	suite.Nilf(err, "Error should be nil")
+	file, diags := hclsyntax.ParseConfig([]byte(generated), "generated.hcl", hcl.Pos{Line: 1, Column: 1})
+	suite.Require().Falsef(diags.HasErrors(), "Generated HCL should parse: %s", diags)
+	body := file.Body.(*hclsyntax.Body)
+	suite.Require().Lenf(body.Blocks, 1, "There should be a single block")
+	suite.Equalf("locals", body.Blocks[0].Type, "Values should be held in locals")
+	locals := body.Blocks[0].Body.Attributes
+	suite.Equalf([]string{"region_input", "tags", "vpc_id"}, sortedKeys(locals), "Locals should avoid module locals")
+	region, _ := locals["region_input"].Expr.Value(nil)
+	suite.Equalf(cty.StringVal("us-west-2"), region, "Locals should hold the defaults")
+	suite.Containsf(generated, `vpc_id       = "TODO" # required: The VPC to deploy into`, "Required locals should be commented")
+	inputs := body.Attributes["inputs"].Expr.(*hclsyntax.ObjectConsExpr)
+	suite.Lenf(inputs.Items, 3, "There should be one input per variable")
+	expected := map[string]string{"region": "region_input", "vpc_id": "vpc_id", "tags": "tags"}
+	for _, item := range inputs.Items {
+		key, _ := item.KeyExpr.Value(nil)
+		traversal, traversalDiags := hcl.AbsTraversalForExpr(item.ValueExpr)
+		suite.Require().Falsef(traversalDiags.HasErrors(), "Input %s should be a reference", key.AsString())
+		suite.Equalf("local", traversal.RootName(), "Input %s should reference a local", key.AsString())
+		suite.Equalf(expected[key.AsString()], traversal[1].(hcl.TraverseAttr).Name, "Input %s should reference its local", key.AsString())
+	}
+}
+
+func (suite *GeneratorTestSuite) Test_localNames_Collisions() {
+	region := &parser.Variable{Name: "region"}
+	regionInput := &parser.Variable{Name: "region_input"}
+	terraform := parser.Terraform{
+		Variables: []*parser.Variable{region, regionInput},
+		Locals:    map[string]string{"region": `"us-east-1"`},
+	}
+	names := localNames(terraform)
+	suite.Equalf("region_input", names[region], "Names colliding with module locals should be suffixed")
+	suite.Equalf("region_input_input", names[regionInput], "Names colliding with earlier picks should be suffixed")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsWithOptions_ChangedSince() {
+	if _, err := exec.LookPath("git"); nil != err {
+		suite.T().Skip("git is not installed")
+	}
+	directory := suite.T().TempDir()
+	filePath := path.Join(directory, "variables.tf")
+	baseline := "variable \"unchanged\" {\n  default = 1\n}\n\nvariable \"changed\" {\n  default = 1\n}\n"
+	suite.Require().Nil(os.WriteFile(filePath, []byte(baseline), 0644))
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "--all"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "baseline"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", directory}, args...)...).CombinedOutput()
+		suite.Require().Nilf(err, "git %v should succeed: %s", args, output)
+	}
+	current := baseline + "\nvariable \"added\" {}\n"
+	current = strings.Replace(current, "variable \"changed\" {\n  default = 1", "variable \"changed\" {\n  default = 2", 1)
+	suite.Require().Nil(os.WriteFile(filePath, []byte(current), 0644))
+	before, err := parser.ParseGitRef("HEAD", filePath)
+	suite.Require().Nilf(err, "The baseline should parse")
+	after, err := parser.ParseFile(filePath)
+	suite.Require().Nilf(err, "The current file should parse")
+	generated, err := GenerateInputsWithOptions(after, RenderOptions{ChangedSince: &before})
+	suite.Nilf(err, "Error should be nil")
+	inputs, diags := suite.parseGenerated(generated)["inputs"].Expr.Value(nil)
+	suite.Require().Falsef(diags.HasErrors(), "Inputs should evaluate: %s", diags)
+	expected := cty.ObjectVal(map[string]cty.Value{
+		"changed": cty.NumberIntVal(2),
+		"added":   cty.StringVal(DefaultRequiredPlaceholder),
+	})
+	suite.Truef(expected.Equals(inputs).True(), "Only added and changed variables should render: %#v", inputs)
+}
+
+func (suite *GeneratorTestSuite) Test_changedVariables_NoBaseline() {
+	variables := []*parser.Variable{{Name: "kept"}}
+	suite.Equalf(variables, changedVariables(variables, nil), "Every variable should be kept without a baseline")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputs_ConditionalDefaults() {
+	generated, err := GenerateInputs(suite.parseFixture(fixtureFileConditionalDefaults))
+	suite.Nilf(err, "Error should be nil")
+	inputs, diags := suite.parseGenerated(generated)["inputs"].Expr.Value(nil)
+	suite.Require().Falsef(diags.HasErrors(), "Inputs should evaluate: %s", diags)
+	suite.Truef(cty.NumberIntVal(3).Equals(inputs.GetAttr("literal")).True(), "Literal conditionals should render their value")
+	suite.Equalf(cty.StringVal(DefaultRequiredPlaceholder), inputs.GetAttr("from_local"), "Unevaluated defaults should get the placeholder")
+	suite.Containsf(generated, "# default: local.production ? 3 : 1", "Unevaluated defaults should be noted")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateJSONSchema_ConditionalDefaults() {
+	generated, err := GenerateJSONSchema(suite.parseFixture(fixtureFileConditionalDefaults))
+	suite.Require().Nilf(err, "Error should be nil")
+	var schema struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+		Required   []string                          `json:"required"`
+	}
+	suite.Require().Nilf(json.Unmarshal(generated, &schema), "Generated schema should be valid JSON")
+	suite.Equalf(float64(3), schema.Properties["literal"]["default"], "Literal conditionals should have a default")
+	suite.NotContainsf(schema.Properties["from_local"], "default", "Unevaluated defaults should be left out")
+	suite.Emptyf(schema.Required, "Variables with defaults should not be required")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputs_RequiredWithoutDescription() {
+	terraform := parser.Terraform{
+		Variables: []*parser.Variable{
+			{Name: "undocumented"},
+		},
+	}
+	generated, err := GenerateInputs(terraform)
+	suite.Nilf(err, "Error should be nil")
+	suite.Containsf(generated, `undocumented = "TODO" # required`+"\n", "Required variables should be marked without a description")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputs_NoVariables() {
+	generated, err := GenerateInputs(parser.Terraform{})
+	suite.Nilf(err, "Error should be nil")
+	suite.Containsf(suite.parseGenerated(generated), "inputs", "Inputs should still be generated")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputs_UnknownDefault() {
+	terraform := parser.Terraform{
+		Variables: []*parser.Variable{
+			{
+				Name:       "unknown",
+				Default:    cty.UnknownVal(cty.String),
+				HasDefault: true,
+			},
+		},
+	}
+	generated, err := GenerateInputs(terraform)
+	suite.Emptyf(generated, "Nothing should be generated")
+	suite.NotNilf(err, "Error should not be nil")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsTo_MatchesGenerateInputs() {
+	grouped, err := parser.Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryGrouped))
+	suite.Require().Nilf(err, "Fixture should parse")
+	cases := []struct {
+		terraform parser.Terraform
+		opts      RenderOptions
+	}{
+		{terraform: parser.Terraform{}},
+		{terraform: suite.parseFixture(fixtureFileInputs)},
+		{terraform: suite.parseFixture(fixtureFileSensitive)},
+		{terraform: suite.parseFixture(fixtureFileCombined), opts: RenderOptions{RequiredPlaceholder: "CHANGEME"}},
+		{terraform: grouped, opts: RenderOptions{GroupBySourceFile: true}},
+		{terraform: grouped, opts: RenderOptions{GroupBySourceFile: true, InputsFromLocals: true}},
+		{terraform: suite.parseFixture(fixtureFileLocals), opts: RenderOptions{InputsFromLocals: true}},
+		{terraform: suite.parseFixture(fixtureFileConditionalDefaults)},
+	}
+	for _, testCase := range cases {
+		expected, err := GenerateInputsWithOptions(testCase.terraform, testCase.opts)
+		suite.Require().Nilf(err, "Error should be nil")
+		var streamed bytes.Buffer
+		suite.Nilf(GenerateInputsTo(&streamed, testCase.terraform, testCase.opts), "Error should be nil")
+		suite.Equalf(expected, streamed.String(), "Streamed inputs should match the in-memory inputs")
+	}
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsTo_ManyVariables() {
+	terraform := parser.Terraform{}
+	for index := 0; index < 1000; index++ {
+		variable := &parser.Variable{Name: fmt.Sprintf("variable_%s", strings.Repeat("x", index%7))}
+		switch index % 4 {
+		case 0:
+			variable.Default, variable.HasDefault = cty.NumberIntVal(int64(index)), true
+		case 1:
+			variable.Default, variable.HasDefault = cty.MapVal(map[string]cty.Value{"key": cty.StringVal("value")}), true
+		case 2:
+			variable.Sensitive = index%3 == 0
+		}
+		terraform.Variables = append(terraform.Variables, variable)
+	}
+	expected, err := GenerateInputs(terraform)
+	suite.Require().Nilf(err, "Error should be nil")
+	var streamed bytes.Buffer
+	suite.Nilf(GenerateInputsTo(&streamed, terraform, RenderOptions{}), "Error should be nil")
+	suite.Equalf(expected, streamed.String(), "Streamed inputs should match the in-memory inputs")
+}
+
+// writeRecorder is a writer that keeps the size of each write
+type writeRecorder struct {
+	sizes []int
+}
+
+func (recorder *writeRecorder) Write(p []byte) (int, error) {
+	recorder.sizes = append(recorder.sizes, len(p))
+	return len(p), nil
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsTo_WritesEachEntry() {
+	terraform := parser.Terraform{}
+	for index := 0; index < 1000; index++ {
+		terraform.Variables = append(terraform.Variables, &parser.Variable{
+			Name:       fmt.Sprintf("variable_%d", index),
+			Default:    cty.NumberIntVal(int64(index)),
+			HasDefault: true,
+		})
+	}
+	recorder := &writeRecorder{}
+	suite.Require().Nilf(GenerateInputsTo(recorder, terraform, RenderOptions{}), "Error should be nil")
+	suite.Lenf(recorder.sizes, len(terraform.Variables)+2, "Every entry should be written on its own between the braces")
+	for _, size := range recorder.sizes {
+		suite.Lessf(size, 64, "No write should hold more than a single entry")
+	}
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateInputsTo_UnknownDefault() {
+	terraform := parser.Terraform{
+		Variables: []*parser.Variable{
+			{
+				Name:       "unknown",
+				Default:    cty.UnknownVal(cty.String),
+				HasDefault: true,
+			},
+		},
+	}
+	var streamed bytes.Buffer
+	suite.NotNilf(GenerateInputsTo(&streamed, terraform, RenderOptions{}), "Error should not be nil")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateDependencies_Success() {
+	generated, err := GenerateDependencies(suite.parseFixture(fixtureFileOutputs), "network")
+	suite.Nilf(err, "Error should be nil")
+	expected := map[string]string{
+		"subnet_ids": "dependency.network.outputs.subnet_ids",
+		"vpc_id":     "dependency.network.outputs.vpc_id",
+	}
+	suite.Equalf(expected, suite.parseDependencies(generated, "network"), "Every output should be passed through")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateDependencies_SkipsUnnamedOutputs() {
+	terraform := parser.Terraform{
+		Outputs: []*parser.Output{
+			{Name: ""},
+			{Name: "named"},
+		},
+	}
+	generated, err := GenerateDependencies(terraform, "network")
+	suite.Nilf(err, "Error should be nil")
+	expected := map[string]string{"named": "dependency.network.outputs.named"}
+	suite.Equalf(expected, suite.parseDependencies(generated, "network"), "Unnamed outputs should be skipped")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateDependencies_NoOutputs() {
+	generated, err := GenerateDependencies(parser.Terraform{}, "network")
+	suite.Nilf(err, "Error should be nil")
+	suite.Emptyf(suite.parseDependencies(generated, "network"), "There should be no inputs")
+	suite.NotContainsf(generated, "inputs", "The inputs stub should be left out")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateDependencies_BlankName() {
+	generated, err := GenerateDependencies(suite.parseFixture(fixtureFileOutputs), "  ")
+	suite.Emptyf(generated, "Nothing should be generated")
+	suite.NotNilf(err, "Error should not be nil")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateDependencies_InvalidName() {
+	generated, err := GenerateDependencies(suite.parseFixture(fixtureFileOutputs), "not valid")
+	suite.Emptyf(generated, "Nothing should be generated")
+	suite.NotNilf(err, "Error should not be nil")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateJSONSchema_Success() {
+	golden, err := ioutil.ReadFile(path.Join(suite.fixtureDirectory, fixtureFileCombinedSchema))
+	suite.Require().Nilf(err, "Golden schema should be readable")
+	generated, err := GenerateJSONSchema(suite.parseFixture(fixtureFileCombined))
+	suite.Nilf(err, "Error should be nil")
+	suite.Truef(json.Valid(generated), "Generated schema should be valid JSON")
+	suite.Equalf(string(golden), string(generated), "Generated schema should match the golden file")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateJSONSchema_OptionalAttributes() {
+	generated, err := GenerateJSONSchema(suite.parseFixture(fixtureFileOptionalAttributes))
+	suite.Require().Nilf(err, "Error should be nil")
+	type objectSchema struct {
+		Properties map[string]*objectSchema `json:"properties"`
+		Required   []string                 `json:"required"`
+	}
+	var schema objectSchema
+	suite.Require().Nilf(json.Unmarshal(generated, &schema), "Generated schema should be valid JSON")
+	settings := schema.Properties["settings"]
+	suite.Require().NotNilf(settings, "The variable should have a property")
+	suite.Equalf([]string{"name"}, settings.Required, "Optional attributes should not be required")
+	suite.Equalf([]string{"format"}, settings.Properties["logging"].Required, "Nested optional attributes should not be required")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateJSONSchema_NoVariables() {
+	generated, err := GenerateJSONSchema(parser.Terraform{})
+	suite.Nilf(err, "Error should be nil")
+	var schema map[string]interface{}
+	suite.Require().Nilf(json.Unmarshal(generated, &schema), "Generated schema should be valid JSON")
+	suite.NotContainsf(schema, "required", "Nothing should be required")
+}
+
+func (suite *GeneratorTestSuite) Test_GenerateJSONSchema_UnknownDefault() {
+	terraform := parser.Terraform{
+		Variables: []*parser.Variable{
+			{
+				Name:       "unknown",
+				Default:    cty.UnknownVal(cty.String),
+				HasDefault: true,
+			},
+		},
+	}
+	generated, err := GenerateJSONSchema(terraform)
+	suite.Nilf(generated, "Nothing should be generated")
+	suite.NotNilf(err, "Error should not be nil")
+}
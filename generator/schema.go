@@ -0,0 +1,127 @@
+// Copyright 2022 CJ Harries
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/wizardsoftheweb/terragrunt-builder/parser"
+)
+
+const (
+	// JSONSchemaDialect is the JSON Schema draft generated schemas declare
+	JSONSchemaDialect = "http://json-schema.org/draft-07/schema#"
+)
+
+// jsonSchema is the subset of JSON Schema needed to describe Terraform types. Field order is the order keys are emitted.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Default              json.RawMessage        `json:"default,omitempty"`
+	Items                interface{}            `json:"items,omitempty"`
+	UniqueItems          bool                   `json:"uniqueItems,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+}
+
+// typeSchema maps a type constraint to a schema. Untyped and any constraints accept anything, so they get an empty
+// schema. Object attributes are required unless declared with optional(...).
+func typeSchema(typeConstraint cty.Type) *jsonSchema {
+	switch {
+	case cty.NilType == typeConstraint || cty.DynamicPseudoType.Equals(typeConstraint):
+		return &jsonSchema{}
+	case cty.String.Equals(typeConstraint):
+		return &jsonSchema{Type: "string"}
+	case cty.Number.Equals(typeConstraint):
+		return &jsonSchema{Type: "number"}
+	case cty.Bool.Equals(typeConstraint):
+		return &jsonSchema{Type: "boolean"}
+	case typeConstraint.IsListType():
+		return &jsonSchema{Type: "array", Items: typeSchema(typeConstraint.ElementType())}
+	case typeConstraint.IsSetType():
+		return &jsonSchema{Type: "array", Items: typeSchema(typeConstraint.ElementType()), UniqueItems: true}
+	case typeConstraint.IsMapType():
+		return &jsonSchema{Type: "object", AdditionalProperties: typeSchema(typeConstraint.ElementType())}
+	case typeConstraint.IsTupleType():
+		var items []*jsonSchema
+		for _, elementType := range typeConstraint.TupleElementTypes() {
+			items = append(items, typeSchema(elementType))
+		}
+		return &jsonSchema{Type: "array", Items: items}
+	case typeConstraint.IsObjectType():
+		schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+		for name, attributeType := range typeConstraint.AttributeTypes() {
+			schema.Properties[name] = typeSchema(attributeType)
+			if !typeConstraint.AttributeOptional(name) {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		sort.Strings(schema.Required)
+		return schema
+	}
+	return &jsonSchema{}
+}
+
+// variableSchema describes a single variable, including its description and default
+func variableSchema(variable *parser.Variable) (*jsonSchema, error) {
+	schema := typeSchema(variable.TypeConstraint)
+	schema.Description = variable.Description
+	// Conditional defaults can't be evaluated here, so the schema only records that there is one by not requiring it
+	if !variable.HasDefault || "" != variable.DefaultExpression {
+		return schema, nil
+	}
+	if !variable.Default.IsWhollyKnown() {
+		return nil, fmt.Errorf("default for variable %s is not known", variable.Name)
+	}
+	defaultJson, err := ctyjson.Marshal(variable.Default, variable.Default.Type())
+	if nil != err {
+		return nil, fmt.Errorf("default for variable %s cannot be rendered as JSON: %w", variable.Name, err)
+	}
+	schema.Default = defaultJson
+	return schema, nil
+}
+
+// GenerateJSONSchema builds a JSON Schema describing the module's inputs, with one property per variable. Variables
+// without a default are required.
+func GenerateJSONSchema(t parser.Terraform) ([]byte, error) {
+	schema := &jsonSchema{
+		Schema:     JSONSchemaDialect,
+		Type:       "object",
+		Properties: map[string]*jsonSchema{},
+	}
+	for _, variable := range t.Variables {
+		property, err := variableSchema(variable)
+		if nil != err {
+			return nil, err
+		}
+		schema.Properties[variable.Name] = property
+		if !variable.HasDefault {
+			schema.Required = append(schema.Required, variable.Name)
+		}
+	}
+	sort.Strings(schema.Required)
+	rendered, err := json.MarshalIndent(schema, "", "  ")
+	if nil != err {
+		return nil, err
+	}
+	return append(rendered, '\n'), nil
+}
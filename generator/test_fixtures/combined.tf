@@ -0,0 +1,42 @@
+variable "name" {
+  description = "Name of the service"
+  type        = string
+}
+
+variable "replicas" {
+  type    = number
+  default = 2
+}
+
+variable "enabled" {
+  type    = bool
+  default = true
+}
+
+variable "zones" {
+  type = set(string)
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "listener" {
+  description = "Load balancer listener"
+  type = object({
+    port = number
+    tls = object({
+      certificate_arn = string
+    })
+  })
+}
+
+variable "pair" {
+  type    = tuple([string, number])
+  default = ["a", 1]
+}
+
+variable "anything" {
+  default = null
+}
@@ -0,0 +1,9 @@
+variable "literal" {
+  type    = number
+  default = true ? 3 : 1
+}
+
+variable "from_local" {
+  type    = number
+  default = local.production ? 3 : 1
+}
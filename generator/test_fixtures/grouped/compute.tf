@@ -0,0 +1,9 @@
+variable "instance_type" {
+  type    = string
+  default = "t3.micro"
+}
+
+variable "ami" {
+  type    = string
+  default = "ami-123"
+}
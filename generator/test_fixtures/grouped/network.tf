@@ -0,0 +1,9 @@
+variable "vpc_name" {
+  type    = string
+  default = "main"
+}
+
+variable "cidr" {
+  type    = string
+  default = "10.0.0.0/16"
+}
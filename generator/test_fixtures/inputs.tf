@@ -0,0 +1,21 @@
+variable "region" {
+  type    = string
+  default = "us-east-1"
+}
+
+variable "zones" {
+  type    = list(string)
+  default = ["a", "b"]
+}
+
+variable "tags" {
+  type = map(string)
+  default = {
+    team = "platform"
+  }
+}
+
+variable "vpc_id" {
+  description = "The VPC to deploy into"
+  type        = string
+}
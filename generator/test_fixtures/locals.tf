@@ -0,0 +1,20 @@
+locals {
+  region = "us-east-1"
+}
+
+variable "region" {
+  type    = string
+  default = "us-west-2"
+}
+
+variable "vpc_id" {
+  description = "The VPC to deploy into"
+  type        = string
+}
+
+variable "tags" {
+  type = map(string)
+  default = {
+    team = "platform"
+  }
+}
@@ -0,0 +1,11 @@
+variable "settings" {
+  description = "Application settings"
+  type = object({
+    name = string
+    port = optional(number, 8080)
+    logging = optional(object({
+      level  = optional(string)
+      format = string
+    }))
+  })
+}
@@ -0,0 +1,15 @@
+variable "password" {
+  type      = string
+  sensitive = true
+}
+
+variable "username" {
+  type    = string
+  default = "admin"
+}
+
+variable "api_token" {
+  type      = string
+  default   = "changeme"
+  sensitive = true
+}
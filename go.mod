@@ -2,7 +2,11 @@ module github.com/wizardsoftheweb/terragrunt-builder
 
 go 1.18
 
-require github.com/hashicorp/hcl/v2 v2.13.0
+require (
+	github.com/hashicorp/hcl/v2 v2.14.1
+	github.com/stretchr/testify v1.8.0
+	github.com/zclconf/go-cty v1.8.0
+)
 
 require (
 	github.com/agext/levenshtein v1.2.1 // indirect
@@ -11,8 +15,6 @@ require (
 	github.com/google/go-cmp v0.3.1 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.8.0 // indirect
-	github.com/zclconf/go-cty v1.8.0 // indirect
 	golang.org/x/text v0.3.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
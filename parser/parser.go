@@ -15,15 +15,28 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
 	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
 
 	"github.com/hashicorp/hcl/v2"
 )
@@ -37,7 +50,68 @@ const (
 	DiagIgnoreUnsupportedArgument = "unsupported argument"
 )
 
+// DedupePolicy decides what happens when a directory declares the same variable more than once
+type DedupePolicy int
+
+const (
+	// DedupeError reports duplicate variables as diagnostics, like Terraform does. The first declaration is kept.
+	DedupeError DedupePolicy = iota
+	// DedupeFirstWins silently keeps the first declaration
+	DedupeFirstWins
+	// DedupeLastWins silently keeps the last declaration, in the position of the first
+	DedupeLastWins
+	// DedupeKeepAll keeps every declaration
+	DedupeKeepAll
+)
+
+// ParseOptions configures ParseWithOptions. The zero value parses the same way Parse does.
+type ParseOptions struct {
+	// Recursive also parses Terraform files in subdirectories into the same result. Hidden directories, like
+	// .terraform, are skipped.
+	Recursive bool
+	// Strict surfaces every unsupported block diagnostic instead of ignoring them, which catches typos like "varaible".
+	// Block types Terraform recognizes, such as resource, are still allowed.
+	Strict bool
+	// Dedupe is the DedupePolicy used when parsing directories
+	Dedupe DedupePolicy
+	// IncludeOverrides loads override files, override.tf and *_override.tf, from directories. Terraform merges these
+	// into the blocks they override, so including them counts those blocks twice.
+	IncludeOverrides bool
+	// Extensions lists the file suffixes loaded from directories. Empty means DefaultExtensions, which covers both .tf
+	// and .tf.json. Files ending in .json are read as JSON and everything else as native syntax.
+	Extensions []string
+}
+
+// extensions resolves the file suffixes loaded from directories
+func (opts ParseOptions) extensions() []string {
+	if 0 == len(opts.Extensions) {
+		return DefaultExtensions
+	}
+	return opts.Extensions
+}
+
 var (
+	// DefaultExtensions are the file suffixes Terraform itself loads from a module directory. .tf.json is included so
+	// directories keep parsing JSON configuration as they did before extensions were configurable.
+	DefaultExtensions = []string{".tf", ".tf.json"}
+	// knownTerraformBlocks lists every top-level block Terraform recognizes, used to tell typos apart in strict mode
+	knownTerraformBlocks = []hcl.BlockHeaderSchema{
+		{Type: "check", LabelNames: []string{"name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "ephemeral", LabelNames: []string{"type", "name"}},
+		{Type: "import"},
+		{Type: "locals"},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "moved"},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "provider", LabelNames: []string{"name"}},
+		{Type: "removed"},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "terraform"},
+		{Type: "variable", LabelNames: []string{"name"}},
+	}
+	// maxObjectAttributeDepth bounds how deeply nested object type constraints are walked
+	maxObjectAttributeDepth = 32
 	// importantBlocksSchema sets up the blocks we're interested in as we parse TF
 	importantBlocksSchema = &hcl.BodySchema{
 		Blocks: []hcl.BlockHeaderSchema{
@@ -49,17 +123,57 @@ var (
 				Type:       "output",
 				LabelNames: []string{"name"},
 			},
+			{
+				Type: "locals",
+			},
+			{
+				Type:       "module",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type: "terraform",
+			},
+		},
+	}
+	// terraformBlockSchema grabs only the nested blocks we're interested in from the terraform block
+	terraformBlockSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       "provider_meta",
+				LabelNames: []string{"provider"},
+			},
+			{
+				Type:       "backend",
+				LabelNames: []string{"type"},
+			},
 		},
 	}
 	// variableBlockSchema grabs only the attributes we're interested in from the variable block
 	variableBlockSchema = &hcl.BodySchema{
 		Attributes: []hcl.AttributeSchema{
+			{
+				Name: "description",
+			},
 			{
 				Name: "type",
 			},
 			{
 				Name: "default",
 			},
+			{
+				Name: "sensitive",
+			},
+		},
+	}
+	// moduleBlockSchema grabs only the attributes we're interested in from the module block
+	moduleBlockSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name: "source",
+			},
+			{
+				Name: "version",
+			},
 		},
 	}
 	outputBlockSchema = &hcl.BodySchema{
@@ -70,32 +184,193 @@ var (
 			{
 				Name: "value",
 			},
+			{
+				Name: "sensitive",
+			},
 		},
 	}
 )
 
+// Position is where a block was declared
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+// newPosition converts the start of an HCL range into a Position
+func newPosition(sourceRange hcl.Range) Position {
+	return Position{
+		Filename: sourceRange.Filename,
+		Line:     sourceRange.Start.Line,
+		Column:   sourceRange.Start.Column,
+	}
+}
+
+// subject converts the position into a zero-width range suitable for a diagnostic subject
+func (position Position) subject() *hcl.Range {
+	pos := hcl.Pos{Line: position.Line, Column: position.Column}
+	return &hcl.Range{Filename: position.Filename, Start: pos, End: pos}
+}
+
 // Variable holds values that may be used for Terragrunt inputs
 type Variable struct {
-	Name    string
-	Default string
+	Name        string
+	Description string
+	// Type is the raw source of the type constraint, e.g. list(string). Type constraints are expressions rather than
+	// values, so we keep them verbatim instead of evaluating them.
+	Type string
+	// TypeConstraint is the resolved type constraint, cty.DynamicPseudoType when the variable is untyped
+	TypeConstraint cty.Type
+	// ObjectAttributes describes the attributes of an object type constraint, or of the element type of a collection
+	// of objects. It is empty for every other type.
+	ObjectAttributes []*ObjectAttribute
+	// Default is the default value, converted to the type constraint when one is declared. It is unknown when the
+	// default is only captured as DefaultExpression.
+	Default cty.Value
+	// DefaultExpression is the raw source of a conditional default that can't be evaluated without the rest of the
+	// module, e.g. local.enabled ? 3 : 1. It is empty for every other default.
+	DefaultExpression string
+	// HasDefault is false when the variable declares no default, i.e. it is a required input
+	HasDefault bool
+	Sensitive  bool
+	Position   Position
+}
+
+// ObjectAttribute describes a single attribute of an object type constraint
+type ObjectAttribute struct {
+	Name string
+	// Type is the canonical type string, e.g. list(string)
+	Type string
+	// Optional is set when the attribute is declared with optional(...)
+	Optional bool
+	// Attributes holds the nested attributes when this attribute is an object or a collection of objects
+	Attributes []*ObjectAttribute
 }
 
 // Output holds values that may be used for Terragrunt dependencies
 type Output struct {
-	Name  string
-	Value string
+	Name string
+	// ValueExpression is the raw source of the value expression. Outputs routinely call functions and reference
+	// resources, so we never try to evaluate them.
+	ValueExpression string
+	Sensitive       bool
+	Position        Position
+}
+
+// Module holds the module calls a configuration makes
+type Module struct {
+	Name     string
+	Source   string
+	Version  string
+	Position Position
+}
+
+// Backend holds the backend configured by a terraform block
+type Backend struct {
+	Type string
+	// Config holds each backend argument's value. Backend configuration can't reference anything, so every argument is
+	// evaluated. Arguments left for -backend-config are missing.
+	Config   map[string]cty.Value
+	Position Position
+}
+
+// ParseError reports the diagnostics that stopped a file, directory, or stream from parsing. Other failures, such as a
+// missing file, are returned as is.
+type ParseError struct {
+	// Path is the file or directory being parsed, or the filename given to ParseReader
+	Path string
+	// Diagnostics holds every problem found
+	Diagnostics hcl.Diagnostics
+}
+
+// Error describes every diagnostic, each of which names its own file
+func (err *ParseError) Error() string {
+	return err.Diagnostics.Error()
+}
+
+// Unwrap exposes the diagnostics so errors.As still works with hcl.Diagnostics
+func (err *ParseError) Unwrap() error {
+	return err.Diagnostics
+}
+
+// asParseError wraps diagnostics in a ParseError, leaving every other error untouched
+func asParseError(err error, filePath string) error {
+	if diags, ok := err.(hcl.Diagnostics); ok {
+		return &ParseError{Path: filePath, Diagnostics: diags}
+	}
+	return err
 }
 
 // Terraform holds the blocks from TF files we're interested in working with
 type Terraform struct {
 	Variables []*Variable
 	Outputs   []*Output
+	Modules   []*Module
+	// Locals maps each local value's name to the raw source of its expression
+	Locals map[string]string
+	// ProviderMeta maps each provider_meta block's provider to its attributes' raw expression source
+	ProviderMeta map[string]map[string]string
+	// Backend is the module's backend, nil when it uses the default local state
+	Backend *Backend
 }
 
-// checkDiagnostics is a simple helper function to ignore diagnostic errors we may not care about. For example, if we're
-// parsing for variables, we may only pass in a schema that contains variables and their structure. Things like
-// resources and outputs would trigger a diagnostic error.
-func checkDiagnostics(diags hcl.Diagnostics, allowedErrors []string) (diagErrors hcl.Diagnostics) {
+// SuggestStateKey recommends a Terragrunt remote_state key expression that keeps the backend key's file name but lays
+// state out by each configuration's path, e.g. "${path_relative_to_include()}/terraform.tfstate". It reports false
+// when there is no backend or its key isn't set.
+func (terraform *Terraform) SuggestStateKey() (string, bool) {
+	if nil == terraform.Backend {
+		return "", false
+	}
+	key, ok := terraform.Backend.Config["key"]
+	if !ok || !key.Type().Equals(cty.String) || !key.IsKnown() || key.IsNull() || "" == strings.Trim(key.AsString(), "/") {
+		return "", false
+	}
+	return fmt.Sprintf(`"${path_relative_to_include()}/%s"`, path.Base(key.AsString())), true
+}
+
+// positionLess orders positions by file, then line, then column
+func positionLess(left Position, right Position) bool {
+	if left.Filename != right.Filename {
+		return left.Filename < right.Filename
+	}
+	if left.Line != right.Line {
+		return left.Line < right.Line
+	}
+	return left.Column < right.Column
+}
+
+// Sort orders variables, outputs, and modules by name, breaking ties by where they were declared, so results don't depend on
+// filesystem iteration order
+func (terraform *Terraform) Sort() {
+	sort.SliceStable(terraform.Variables, func(i, j int) bool {
+		left, right := terraform.Variables[i], terraform.Variables[j]
+		if left.Name != right.Name {
+			return left.Name < right.Name
+		}
+		return positionLess(left.Position, right.Position)
+	})
+	sort.SliceStable(terraform.Outputs, func(i, j int) bool {
+		left, right := terraform.Outputs[i], terraform.Outputs[j]
+		if left.Name != right.Name {
+			return left.Name < right.Name
+		}
+		return positionLess(left.Position, right.Position)
+	})
+	sort.SliceStable(terraform.Modules, func(i, j int) bool {
+		left, right := terraform.Modules[i], terraform.Modules[j]
+		if left.Name != right.Name {
+			return left.Name < right.Name
+		}
+		return positionLess(left.Position, right.Position)
+	})
+}
+
+// FilterDiagnostics drops the error diagnostics we may not care about. For example, if we're parsing for variables, we
+// may only pass in a schema that contains variables and their structure. Things like resources and outputs would
+// trigger a diagnostic error. An error is dropped when its message, which includes both its summary and detail, contains
+// any of the allowed strings, ignoring case. Without any allowed strings the diagnostics are returned untouched.
+func FilterDiagnostics(diags hcl.Diagnostics, allowedErrors []string) (diagErrors hcl.Diagnostics) {
 	if 0 == len(allowedErrors) {
 		return diags
 	}
@@ -122,39 +397,280 @@ func loadFile(filePath string) (rawHcl *hcl.File, err error) {
 	if fileReadErr != nil {
 		return nil, fileReadErr
 	}
-	rawHcl, hclParseDiags := hclsyntax.ParseConfig(fileContents, filePath, hcl.Pos{Line: 1, Column: 1})
+	return loadContents(fileContents, filePath)
+}
+
+// loadContents parses raw contents into a raw HCL format. Filenames ending in .json are parsed with the JSON syntax,
+// everything else with the native syntax. The filename is otherwise only used for diagnostic ranges.
+func loadContents(contents []byte, filename string) (rawHcl *hcl.File, err error) {
+	var hclParseDiags hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		rawHcl, hclParseDiags = hcljson.Parse(contents, filename)
+	} else {
+		rawHcl, hclParseDiags = hclsyntax.ParseConfig(contents, filename, hcl.Pos{Line: 1, Column: 1})
+	}
 	if hclParseDiags.HasErrors() {
 		return nil, hclParseDiags
 	}
 	return rawHcl, nil
 }
 
+// jsonString returns the value of a JSON syntax string expression. The second return is false for native syntax
+// expressions and anything that isn't a plain string.
+func jsonString(expr hcl.Expression) (string, bool) {
+	if _, isNative := expr.(hclsyntax.Expression); isNative {
+		return "", false
+	}
+	// Without an evaluation context JSON strings are returned literally rather than as templates
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || cty.String != value.Type() || !value.IsKnown() || value.IsNull() {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// typeSource returns the source text of a type constraint. JSON syntax writes type constraints as strings containing
+// native syntax, so the contents of the string are used.
+func typeSource(expr hcl.Expression, source []byte) string {
+	if typeString, ok := jsonString(expr); ok {
+		return typeString
+	}
+	return string(expr.Range().SliceBytes(source))
+}
+
+// expressionSource returns the source text of an expression. JSON syntax writes expressions as "${...}" templates, so
+// a string holding nothing but a single interpolation is unwrapped to match the native syntax.
+func expressionSource(expr hcl.Expression, source []byte) string {
+	if template, ok := jsonString(expr); ok {
+		parsed, diags := hclsyntax.ParseTemplate([]byte(template), expr.Range().Filename, hcl.Pos{Line: 1, Column: 1})
+		if wrapped, isWrapped := parsed.(*hclsyntax.TemplateWrapExpr); isWrapped && !diags.HasErrors() {
+			return string(wrapped.Wrapped.Range().SliceBytes([]byte(template)))
+		}
+	}
+	return string(expr.Range().SliceBytes(source))
+}
+
+// withKnownBlocks copies the schema, adding every top-level Terraform block type it doesn't already cover
+func withKnownBlocks(schema *hcl.BodySchema) *hcl.BodySchema {
+	knownSchema := &hcl.BodySchema{
+		Attributes: schema.Attributes,
+		Blocks:     append([]hcl.BlockHeaderSchema{}, schema.Blocks...),
+	}
+	for _, knownBlock := range knownTerraformBlocks {
+		covered := false
+		for _, block := range schema.Blocks {
+			if block.Type == knownBlock.Type {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			knownSchema.Blocks = append(knownSchema.Blocks, knownBlock)
+		}
+	}
+	return knownSchema
+}
+
 // processSchema is a helper function to process the raw HCL format into something that can be walked and parsed
-func processSchema(rawHcl *hcl.File, schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+func processSchema(rawHcl *hcl.File, schema *hcl.BodySchema, strict bool) (*hcl.BodyContent, hcl.Diagnostics) {
+	allowedErrors := []string{DiagIgnoreUnsupportedBlock}
+	if strict {
+		// Only blocks Terraform itself wouldn't recognize should fail, so everything else it knows joins the schema
+		schema = withKnownBlocks(schema)
+		allowedErrors = nil
+	}
 	blocks, diags := rawHcl.Body.Content(schema)
-	diagErrs := checkDiagnostics(diags, []string{DiagIgnoreUnsupportedBlock})
+	diagErrs := FilterDiagnostics(diags, allowedErrors)
 	if nil != diagErrs {
 		return nil, diagErrs
 	}
 	return blocks, nil
 }
 
-// processVariable turns a variable block into a variable struct
-func processVariable(block *hcl.Block) (variable *Variable, diagErr hcl.Diagnostics) {
+// processDefault evaluates a variable's default and converts it to the variable's type constraint the same way
+// Terraform does. Untyped variables use cty.DynamicPseudoType, which leaves the value untouched.
+func processDefault(defaultAttr *hcl.Attribute, typeConstraint cty.Type, typeDefaults *typeexpr.Defaults) (cty.Value, hcl.Diagnostics) {
+	// Referencing another variable is a common mistake that otherwise surfaces as an opaque evaluation error
+	var referenceDiags hcl.Diagnostics
+	for _, traversal := range defaultAttr.Expr.Variables() {
+		if "var" == traversal.RootName() {
+			referenceDiags = append(referenceDiags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid default value for variable",
+				Detail:   "Variable defaults cannot reference other variables.",
+				Subject:  traversal.SourceRange().Ptr(),
+			})
+		}
+	}
+	if nil != referenceDiags {
+		return cty.NilVal, referenceDiags
+	}
+	defaultValue, diags := defaultAttr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	// cty will happily turn "123" into 123, but a quoted number or bool against a number or bool type is almost always a
+	// mistake. Going the other way, like 8080 against a string type, is ordinary Terraform and is left to convert.
+	if cty.String.Equals(defaultValue.Type()) && (cty.Number.Equals(typeConstraint) || cty.Bool.Equals(typeConstraint)) {
+		return cty.NilVal, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid default value for variable",
+				Detail: fmt.Sprintf(
+					"This default value is a %s but the variable's type constraint is %s.",
+					defaultValue.Type().FriendlyName(), typeConstraint.FriendlyName(),
+				),
+				Subject: defaultAttr.Expr.Range().Ptr(),
+			},
+		}
+	}
+	// Defaults for optional() attributes are filled in before conversion, as Terraform does
+	if nil != typeDefaults {
+		defaultValue = typeDefaults.Apply(defaultValue)
+	}
+	convertedValue, convertErr := convert.Convert(defaultValue, typeConstraint)
+	if nil != convertErr {
+		return cty.NilVal, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid default value for variable",
+				Detail:   fmt.Sprintf("This default value is not compatible with the variable's type constraint: %s.", convertErr),
+				Subject:  defaultAttr.Expr.Range().Ptr(),
+			},
+		}
+	}
+	return convertedValue, nil
+}
+
+// isDeferredDefault reports whether a default is a conditional that only the rest of the module, e.g. its locals, can
+// evaluate. Conditionals over literals evaluate normally, and ones referencing variables are still reported as invalid.
+func isDeferredDefault(defaultAttr *hcl.Attribute) bool {
+	if _, ok := defaultAttr.Expr.(*hclsyntax.ConditionalExpr); !ok {
+		return false
+	}
+	for _, traversal := range defaultAttr.Expr.Variables() {
+		if "var" == traversal.RootName() {
+			return false
+		}
+	}
+	_, diags := defaultAttr.Expr.Value(nil)
+	return diags.HasErrors()
+}
+
+// processObjectAttributes walks an object type constraint, or a collection of objects, into attribute metadata. The
+// depth is tracked so pathological nesting produces a diagnostic instead of unbounded recursion.
+func processObjectAttributes(typeConstraint cty.Type, depth int, subject hcl.Range) ([]*ObjectAttribute, hcl.Diagnostics) {
+	for typeConstraint.IsCollectionType() {
+		typeConstraint = typeConstraint.ElementType()
+	}
+	if !typeConstraint.IsObjectType() {
+		return nil, nil
+	}
+	if depth > maxObjectAttributeDepth {
+		return nil, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Type constraint nested too deeply",
+				Detail:   fmt.Sprintf("Object type constraints may be nested at most %d levels deep.", maxObjectAttributeDepth),
+				Subject:  subject.Ptr(),
+			},
+		}
+	}
+	attributeTypes := typeConstraint.AttributeTypes()
+	names := make([]string, 0, len(attributeTypes))
+	for name := range attributeTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attributes := make([]*ObjectAttribute, 0, len(names))
+	for _, name := range names {
+		attribute := &ObjectAttribute{
+			Name:     name,
+			Type:     typeexpr.TypeString(attributeTypes[name]),
+			Optional: typeConstraint.AttributeOptional(name),
+		}
+		nestedAttributes, diagErr := processObjectAttributes(attributeTypes[name], depth+1, subject)
+		if nil != diagErr {
+			return nil, diagErr
+		}
+		attribute.Attributes = nestedAttributes
+		attributes = append(attributes, attribute)
+	}
+	return attributes, nil
+}
+
+// validVariableName checks a variable name is an identifier. HCL itself tolerates dashes in identifiers, but they
+// don't survive the trip to every tool downstream of us, so they're rejected here.
+func validVariableName(name string) bool {
+	return hclsyntax.ValidIdentifier(name) && !strings.Contains(name, "-")
+}
+
+// processVariable turns a variable block into a variable struct. The source is the raw file contents, used to capture
+// expressions verbatim.
+func processVariable(block *hcl.Block, source []byte) (variable *Variable, diagErr hcl.Diagnostics) {
 	if "variable" != block.Type {
 		return nil, nil
 	}
+	if !validVariableName(block.Labels[0]) {
+		return nil, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid variable name",
+				Detail: fmt.Sprintf(
+					"%q is not a valid variable name. A name must start with a letter or underscore and may contain only letters, digits, and underscores.",
+					block.Labels[0],
+				),
+				Subject: block.LabelRanges[0].Ptr(),
+			},
+		}
+	}
 	blockContent, diags := block.Body.Content(variableBlockSchema)
-	diagErr = checkDiagnostics(diags, []string{DiagIgnoreUnsupportedAttribute, DiagIgnoreUnsupportedArgument})
+	diagErr = FilterDiagnostics(diags, []string{DiagIgnoreUnsupportedAttribute, DiagIgnoreUnsupportedArgument})
 	if nil != diagErr {
 		return nil, diagErr
 	}
 	variable = &Variable{
-		Name: block.Labels[0],
+		Name:     block.Labels[0],
+		Position: newPosition(block.DefRange),
+	}
+	if descriptionAttr, ok := blockContent.Attributes["description"]; ok {
+		attributeDiags := gohcl.DecodeExpression(descriptionAttr.Expr, nil, &variable.Description)
+		diagErr = FilterDiagnostics(attributeDiags, nil)
+		if nil != attributeDiags {
+			return nil, diagErr
+		}
+	}
+	variable.TypeConstraint = cty.DynamicPseudoType
+	var typeDefaults *typeexpr.Defaults
+	if typeAttr, ok := blockContent.Attributes["type"]; ok {
+		variable.Type = typeSource(typeAttr.Expr, source)
+		// A constraint this version of HCL can't resolve shouldn't drop the variable, so it falls back to any and
+		// keeps the raw type
+		typeConstraint, defaults, typeDiags := typeexpr.TypeConstraintWithDefaults(typeAttr.Expr)
+		if !typeDiags.HasErrors() {
+			variable.TypeConstraint = typeConstraint
+			typeDefaults = defaults
+			variable.ObjectAttributes, diagErr = processObjectAttributes(variable.TypeConstraint, 1, typeAttr.Expr.Range())
+			if nil != diagErr {
+				return nil, diagErr
+			}
+		}
 	}
 	if defaultAttr, ok := blockContent.Attributes["default"]; ok {
-		attributeDiags := gohcl.DecodeExpression(defaultAttr.Expr, nil, &variable.Default)
-		diagErr = checkDiagnostics(attributeDiags, nil)
+		if isDeferredDefault(defaultAttr) {
+			variable.Default = cty.UnknownVal(variable.TypeConstraint)
+			variable.DefaultExpression = expressionSource(defaultAttr.Expr, source)
+		} else {
+			variable.Default, diagErr = processDefault(defaultAttr, variable.TypeConstraint, typeDefaults)
+			if nil != diagErr {
+				return nil, diagErr
+			}
+		}
+		variable.HasDefault = true
+	}
+	if sensitiveAttr, ok := blockContent.Attributes["sensitive"]; ok {
+		attributeDiags := gohcl.DecodeExpression(sensitiveAttr.Expr, nil, &variable.Sensitive)
+		diagErr = FilterDiagnostics(attributeDiags, nil)
 		if nil != attributeDiags {
 			return nil, diagErr
 		}
@@ -162,22 +678,27 @@ func processVariable(block *hcl.Block) (variable *Variable, diagErr hcl.Diagnost
 	return variable, nil
 }
 
-// processOutput turns an output block into an output struct
-func processOutput(block *hcl.Block) (output *Output, diagErr hcl.Diagnostics) {
+// processOutput turns an output block into an output struct. The source is the raw file contents, used to capture
+// expressions verbatim.
+func processOutput(block *hcl.Block, source []byte) (output *Output, diagErr hcl.Diagnostics) {
 	if "output" != block.Type {
 		return nil, nil
 	}
 	blockContent, diags := block.Body.Content(outputBlockSchema)
-	diagErr = checkDiagnostics(diags, []string{DiagIgnoreUnsupportedAttribute, DiagIgnoreUnsupportedArgument})
+	diagErr = FilterDiagnostics(diags, []string{DiagIgnoreUnsupportedAttribute, DiagIgnoreUnsupportedArgument})
 	if nil != diagErr {
 		return nil, diagErr
 	}
 	output = &Output{
-		Name: block.Labels[0],
+		Name:     block.Labels[0],
+		Position: newPosition(block.DefRange),
 	}
 	if valueAttr, ok := blockContent.Attributes["value"]; ok {
-		attributeDiags := gohcl.DecodeExpression(valueAttr.Expr, nil, &output.Value)
-		diagErr = checkDiagnostics(attributeDiags, nil)
+		output.ValueExpression = expressionSource(valueAttr.Expr, source)
+	}
+	if sensitiveAttr, ok := blockContent.Attributes["sensitive"]; ok {
+		attributeDiags := gohcl.DecodeExpression(sensitiveAttr.Expr, nil, &output.Sensitive)
+		diagErr = FilterDiagnostics(attributeDiags, nil)
 		if nil != attributeDiags {
 			return nil, diagErr
 		}
@@ -185,71 +706,545 @@ func processOutput(block *hcl.Block) (output *Output, diagErr hcl.Diagnostics) {
 	return output, nil
 }
 
-func processTerraform(body *hcl.BodyContent) (terraform Terraform, diagErrs hcl.Diagnostics) {
+// processModule turns a module block into a module struct
+func processModule(block *hcl.Block) (module *Module, diagErr hcl.Diagnostics) {
+	if "module" != block.Type {
+		return nil, nil
+	}
+	blockContent, diags := block.Body.Content(moduleBlockSchema)
+	diagErr = FilterDiagnostics(diags, []string{DiagIgnoreUnsupportedAttribute, DiagIgnoreUnsupportedArgument})
+	if nil != diagErr {
+		return nil, diagErr
+	}
+	module = &Module{
+		Name:     block.Labels[0],
+		Position: newPosition(block.DefRange),
+	}
+	if sourceAttr, ok := blockContent.Attributes["source"]; ok {
+		attributeDiags := gohcl.DecodeExpression(sourceAttr.Expr, nil, &module.Source)
+		diagErr = FilterDiagnostics(attributeDiags, nil)
+		if nil != attributeDiags {
+			return nil, diagErr
+		}
+	}
+	if versionAttr, ok := blockContent.Attributes["version"]; ok {
+		attributeDiags := gohcl.DecodeExpression(versionAttr.Expr, nil, &module.Version)
+		diagErr = FilterDiagnostics(attributeDiags, nil)
+		if nil != attributeDiags {
+			return nil, diagErr
+		}
+	}
+	return module, nil
+}
+
+// processLocals turns a locals block into a map of names to raw expression source. Locals have arbitrary names, so
+// there is no schema to apply.
+func processLocals(block *hcl.Block, source []byte) (locals map[string]string, diagErr hcl.Diagnostics) {
+	if "locals" != block.Type {
+		return nil, nil
+	}
+	attributes, diags := block.Body.JustAttributes()
+	diagErr = FilterDiagnostics(diags, nil)
+	if nil != diagErr {
+		return nil, diagErr
+	}
+	locals = map[string]string{}
+	for name, attribute := range attributes {
+		locals[name] = expressionSource(attribute.Expr, source)
+	}
+	return locals, nil
+}
+
+// mergeLocals adds locals to an existing set, reporting any name defined twice against the subject
+func mergeLocals(into map[string]string, from map[string]string, subject *hcl.Range) (map[string]string, hcl.Diagnostics) {
+	if nil == into && 0 < len(from) {
+		into = map[string]string{}
+	}
+	names := make([]string, 0, len(from))
+	for name := range from {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var diagErrs hcl.Diagnostics
+	for _, name := range names {
+		if _, exists := into[name]; exists {
+			diagErrs = append(diagErrs, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate local value definition",
+				Detail:   fmt.Sprintf("A local value named %q was already defined. Local value names must be unique within a module.", name),
+				Subject:  subject,
+			})
+			continue
+		}
+		into[name] = from[name]
+	}
+	return into, diagErrs
+}
+
+// processTerraformBlock pulls the provider_meta blocks, as a map of provider names to raw attribute source, and the
+// backend out of a terraform block. Everything else in the block, like required_providers, is skipped.
+func processTerraformBlock(block *hcl.Block, source []byte) (providerMeta map[string]map[string]string, backend *Backend, diagErr hcl.Diagnostics) {
+	if "terraform" != block.Type {
+		return nil, nil, nil
+	}
+	blockContent, diags := block.Body.Content(terraformBlockSchema)
+	diagErr = FilterDiagnostics(diags, []string{DiagIgnoreUnsupportedBlock, DiagIgnoreUnsupportedArgument})
+	if nil != diagErr {
+		return nil, nil, diagErr
+	}
+	for _, nestedBlock := range blockContent.Blocks {
+		attributes, attributeDiags := nestedBlock.Body.JustAttributes()
+		diagErr = FilterDiagnostics(attributeDiags, nil)
+		if nil != diagErr {
+			return nil, nil, diagErr
+		}
+		var mergeDiags hcl.Diagnostics
+		switch nestedBlock.Type {
+		case "provider_meta":
+			meta := map[string]string{}
+			for name, attribute := range attributes {
+				meta[name] = expressionSource(attribute.Expr, source)
+			}
+			providerMeta, mergeDiags = mergeProviderMeta(providerMeta, map[string]map[string]string{nestedBlock.Labels[0]: meta}, nestedBlock.DefRange.Ptr())
+		case "backend":
+			nestedBackend := &Backend{
+				Type:     nestedBlock.Labels[0],
+				Config:   map[string]cty.Value{},
+				Position: newPosition(nestedBlock.DefRange),
+			}
+			for name, attribute := range attributes {
+				value, valueDiags := attribute.Expr.Value(nil)
+				if valueDiags.HasErrors() {
+					return nil, nil, valueDiags
+				}
+				nestedBackend.Config[name] = value
+			}
+			backend, mergeDiags = mergeBackend(backend, nestedBackend)
+		}
+		if nil != mergeDiags {
+			return nil, nil, mergeDiags
+		}
+	}
+	return providerMeta, backend, nil
+}
+
+// mergeBackend keeps the first backend configured, reporting any later one since a module may only have one
+func mergeBackend(into *Backend, from *Backend) (*Backend, hcl.Diagnostics) {
+	if nil == into {
+		return from, nil
+	}
+	if nil == from {
+		return into, nil
+	}
+	return into, hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Duplicate backend configuration",
+			Detail: fmt.Sprintf(
+				"A module may have only one backend configuration. The backend was previously configured at %s:%d,%d.",
+				into.Position.Filename, into.Position.Line, into.Position.Column,
+			),
+			Subject: from.Position.subject(),
+		},
+	}
+}
+
+// mergeProviderMeta adds provider_meta blocks to an existing set, reporting any provider given twice against the subject
+func mergeProviderMeta(into map[string]map[string]string, from map[string]map[string]string, subject *hcl.Range) (map[string]map[string]string, hcl.Diagnostics) {
+	if nil == into && 0 < len(from) {
+		into = map[string]map[string]string{}
+	}
+	providers := make([]string, 0, len(from))
+	for provider := range from {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	var diagErrs hcl.Diagnostics
+	for _, provider := range providers {
+		if _, exists := into[provider]; exists {
+			diagErrs = append(diagErrs, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate provider_meta block",
+				Detail:   fmt.Sprintf("A provider_meta block for provider %q was already defined. Each provider may only have one.", provider),
+				Subject:  subject,
+			})
+			continue
+		}
+		into[provider] = from[provider]
+	}
+	return into, diagErrs
+}
+
+func processTerraform(body *hcl.BodyContent, source []byte) (terraform Terraform, diagErrs hcl.Diagnostics) {
 	for _, block := range body.Blocks {
 		switch block.Type {
 		case "variable":
-			variable, diagErr := processVariable(block)
+			variable, diagErr := processVariable(block, source)
 			if nil != diagErr {
 				diagErrs = append(diagErrs, diagErr...)
 				continue
 			}
 			terraform.Variables = append(terraform.Variables, variable)
 		case "output":
-			output, diagErr := processOutput(block)
+			output, diagErr := processOutput(block, source)
 			if nil != diagErr {
 				diagErrs = append(diagErrs, diagErr...)
 				continue
 			}
 			terraform.Outputs = append(terraform.Outputs, output)
+		case "module":
+			module, diagErr := processModule(block)
+			if nil != diagErr {
+				diagErrs = append(diagErrs, diagErr...)
+				continue
+			}
+			terraform.Modules = append(terraform.Modules, module)
+		case "locals":
+			locals, diagErr := processLocals(block, source)
+			if nil != diagErr {
+				diagErrs = append(diagErrs, diagErr...)
+				continue
+			}
+			var mergeDiags hcl.Diagnostics
+			terraform.Locals, mergeDiags = mergeLocals(terraform.Locals, locals, block.DefRange.Ptr())
+			diagErrs = append(diagErrs, mergeDiags...)
+		case "terraform":
+			providerMeta, backend, diagErr := processTerraformBlock(block, source)
+			if nil != diagErr {
+				diagErrs = append(diagErrs, diagErr...)
+				continue
+			}
+			var mergeDiags hcl.Diagnostics
+			terraform.ProviderMeta, mergeDiags = mergeProviderMeta(terraform.ProviderMeta, providerMeta, block.DefRange.Ptr())
+			diagErrs = append(diagErrs, mergeDiags...)
+			terraform.Backend, mergeDiags = mergeBackend(terraform.Backend, backend)
+			diagErrs = append(diagErrs, mergeDiags...)
 		}
 	}
 	return terraform, diagErrs
 }
 
-func processFile(filePath string) (Terraform, error) {
-	terraform := Terraform{}
+// processRawHcl runs the schema and block processing over a loaded file. Blocks that fail, like a variable with an
+// invalid name, are skipped and reported while everything else in the file is still returned.
+func processRawHcl(rawHcl *hcl.File, opts ParseOptions) (Terraform, error) {
+	body, diagErrs := processSchema(rawHcl, importantBlocksSchema, opts.Strict)
+	if nil != diagErrs {
+		return Terraform{}, diagErrs
+	}
+	terraform, diagErrs := processTerraform(body, rawHcl.Bytes)
+	if diagErrs.HasErrors() {
+		return terraform, diagErrs
+	}
+	return terraform, nil
+}
+
+// ParseFile parses exactly one Terraform file. Use Parse when the path might be a directory. Blocks that fail are
+// reported in a ParseError alongside everything else the file contained.
+func ParseFile(filePath string) (Terraform, error) {
+	return parseFile(filePath, ParseOptions{})
+}
+
+// parseFile parses exactly one Terraform file with the given options
+func parseFile(filePath string, opts ParseOptions) (Terraform, error) {
 	rawHcl, err := loadFile(filePath)
 	if nil != err {
-		return terraform, err
+		// Only pay for the stat when reading failed so the happy path stays a single read
+		if fileInfo, statErr := os.Stat(filePath); nil == statErr && fileInfo.IsDir() {
+			return Terraform{}, fmt.Errorf("%s is a directory; use Parse for directories", filePath)
+		}
+		return Terraform{}, asParseError(err, filePath)
 	}
-	body, diagErrs := processSchema(rawHcl, importantBlocksSchema)
-	if nil != diagErrs {
-		return terraform, diagErrs
+	terraform, err := processRawHcl(rawHcl, opts)
+	if nil != err {
+		return terraform, asParseError(err, filePath)
 	}
-	terraform, diagErrs = processTerraform(body)
-	if diagErrs.HasErrors() {
-		return Terraform{}, diagErrs
+	return terraform, nil
+}
+
+// ParseReader parses Terraform from a stream. The filename picks the syntax, like ParseFile, and is used for
+// diagnostic ranges; nothing is read from disk.
+func ParseReader(r io.Reader, filename string) (Terraform, error) {
+	contents, readErr := io.ReadAll(r)
+	if nil != readErr {
+		return Terraform{}, readErr
+	}
+	rawHcl, err := loadContents(contents, filename)
+	if nil != err {
+		return Terraform{}, asParseError(err, filename)
+	}
+	terraform, err := processRawHcl(rawHcl, ParseOptions{})
+	if nil != err {
+		return terraform, asParseError(err, filename)
 	}
 	return terraform, nil
 }
 
+// ParseGitRef parses a single Terraform file as it was at a git ref, e.g. a branch or commit, by asking git for its
+// contents. The file's directory must be inside a git work tree.
+func ParseGitRef(ref string, filePath string) (Terraform, error) {
+	// git would read a ref like --output=<path> as an option rather than a revision
+	if "" == ref || strings.HasPrefix(ref, "-") {
+		return Terraform{}, fmt.Errorf("invalid git ref %q", ref)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", filepath.Dir(filePath), "show", fmt.Sprintf("%s:./%s", ref, filepath.Base(filePath)))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); nil != err {
+		if message := strings.TrimSpace(stderr.String()); "" != message {
+			return Terraform{}, fmt.Errorf("unable to read %s at %s: %w: %s", filePath, ref, err, message)
+		}
+		return Terraform{}, fmt.Errorf("unable to read %s at %s: %w", filePath, ref, err)
+	}
+	return ParseReader(&stdout, filePath)
+}
+
+// mergeTerraform appends everything from one result to another, reporting clashing locals and provider_meta blocks
+// against the subject, and clashing backends against themselves. Variables are left for dedupeVariables.
+func mergeTerraform(into Terraform, from Terraform, subject *hcl.Range) (Terraform, hcl.Diagnostics) {
+	into.Variables = append(into.Variables, from.Variables...)
+	into.Outputs = append(into.Outputs, from.Outputs...)
+	into.Modules = append(into.Modules, from.Modules...)
+	var diagErrs, mergeDiags hcl.Diagnostics
+	into.Locals, mergeDiags = mergeLocals(into.Locals, from.Locals, subject)
+	diagErrs = append(diagErrs, mergeDiags...)
+	into.ProviderMeta, mergeDiags = mergeProviderMeta(into.ProviderMeta, from.ProviderMeta, subject)
+	diagErrs = append(diagErrs, mergeDiags...)
+	into.Backend, mergeDiags = mergeBackend(into.Backend, from.Backend)
+	diagErrs = append(diagErrs, mergeDiags...)
+	return into, diagErrs
+}
+
+// Merge combines two results, e.g. from separately parsed directories, by concatenating everything in them. Nothing
+// is deduplicated or checked; use MergeWithPolicy for that. Neither result is modified. Locals and provider_meta
+// blocks defined by both keep the receiver's, as does the backend.
+func (terraform Terraform) Merge(other Terraform) Terraform {
+	// Merging into a fresh result keeps appends from writing into either side's slices
+	merged, _ := mergeTerraform(Terraform{}, terraform, nil)
+	merged, _ = mergeTerraform(merged, other, nil)
+	return merged
+}
+
+// MergeWithPolicy combines two results the same way a directory's files are combined. Variables are deduplicated by
+// policy, clashing locals, provider_meta blocks, and backends are reported, and the result is sorted. On error the
+// merged result is still returned alongside the hcl.Diagnostics.
+func (terraform Terraform) MergeWithPolicy(other Terraform, policy DedupePolicy) (Terraform, error) {
+	merged, diagErrs := mergeTerraform(Terraform{}, terraform, nil)
+	merged, mergeDiags := mergeTerraform(merged, other, nil)
+	diagErrs = append(diagErrs, mergeDiags...)
+	var dedupeDiags hcl.Diagnostics
+	merged.Variables, dedupeDiags = dedupeVariables(merged.Variables, policy)
+	diagErrs = append(diagErrs, dedupeDiags...)
+	merged.Sort()
+	if diagErrs.HasErrors() {
+		return merged, diagErrs
+	}
+	return merged, nil
+}
+
+// dedupeVariables collapses variables sharing a name according to the policy, preserving the order of first
+// declarations
+func dedupeVariables(variables []*Variable, policy DedupePolicy) (deduped []*Variable, diagErrs hcl.Diagnostics) {
+	if DedupeKeepAll == policy {
+		return variables, nil
+	}
+	indices := map[string]int{}
+	for _, variable := range variables {
+		index, seen := indices[variable.Name]
+		if !seen {
+			indices[variable.Name] = len(deduped)
+			deduped = append(deduped, variable)
+			continue
+		}
+		switch policy {
+		case DedupeLastWins:
+			deduped[index] = variable
+		case DedupeError:
+			first := deduped[index].Position
+			diagErrs = append(diagErrs, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate variable declaration",
+				Detail: fmt.Sprintf(
+					"A variable named %q was already declared at %s:%d,%d. Variable names must be unique within a module.",
+					variable.Name, first.Filename, first.Line, first.Column,
+				),
+				Subject: variable.Position.subject(),
+			})
+		}
+	}
+	return deduped, diagErrs
+}
+
+// asDiagnostics turns any error into diagnostics so failures from several files can be reported together
+func asDiagnostics(err error, filePath string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		return diags
+	}
+	return hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to parse file",
+			Detail:   err.Error(),
+			Subject:  &hcl.Range{Filename: filePath},
+		},
+	}
+}
+
+// matchExtension finds the extension a file name ends in, reporting false when it ends in none of them
+func matchExtension(name string, extensions []string) (string, bool) {
+	for _, extension := range extensions {
+		if strings.HasSuffix(name, extension) {
+			return extension, true
+		}
+	}
+	return "", false
+}
+
+// isOverrideFile reports whether a file name, stripped of its extension, follows Terraform's override naming
+func isOverrideFile(name string, extension string) bool {
+	stem := strings.TrimSuffix(name, extension)
+	return "override" == stem || strings.HasSuffix(stem, "_override")
+}
+
+// terraformFiles lists the Terraform files in a directory, descending into subdirectories when opts are recursive
+func terraformFiles(directory string, opts ParseOptions) ([]string, error) {
+	files, err := ioutil.ReadDir(directory)
+	if nil != err {
+		return nil, err
+	}
+	var filePaths []string
+	for _, file := range files {
+		childPath := path.Join(directory, file.Name())
+		if file.IsDir() {
+			if !opts.Recursive || strings.HasPrefix(file.Name(), ".") {
+				continue
+			}
+			childPaths, childErr := terraformFiles(childPath, opts)
+			if nil != childErr {
+				return nil, childErr
+			}
+			filePaths = append(filePaths, childPaths...)
+			continue
+		}
+		extension, matched := matchExtension(file.Name(), opts.extensions())
+		if !matched || (!opts.IncludeOverrides && isOverrideFile(file.Name(), extension)) {
+			continue
+		}
+		filePaths = append(filePaths, childPath)
+	}
+	return filePaths, nil
+}
+
+// Parse parses a single Terraform file or every Terraform file in a directory using the default ParseOptions.
+// Directories are not walked recursively; subdirectories such as modules/ hold separate modules and must be parsed
+// explicitly. When some files in a directory fail, the diagnostics from every file are returned together alongside
+// whatever the good files contained. Results from a directory are sorted; a single file keeps its source order.
 func Parse(filePath string) (Terraform, error) {
+	return ParseWithOptions(filePath, ParseOptions{})
+}
+
+// ParseWithOptions parses a single Terraform file or every Terraform file in a directory, like Parse, configured by
+// opts.
+func ParseWithOptions(filePath string, opts ParseOptions) (Terraform, error) {
+	return parse(context.Background(), filePath, opts, parseFile)
+}
+
+// ParseContext parses like Parse, but stops with ctx.Err() once ctx is cancelled. Directories are checked between
+// files and single files only before they're read.
+func ParseContext(ctx context.Context, filePath string) (Terraform, error) {
+	return parse(ctx, filePath, ParseOptions{}, parseFile)
+}
+
+// parse parses a single Terraform file or every Terraform file in a directory, each with fileParser, until ctx is
+// cancelled
+func parse(ctx context.Context, filePath string, opts ParseOptions, fileParser func(string, ParseOptions) (Terraform, error)) (Terraform, error) {
+	if err := ctx.Err(); nil != err {
+		return Terraform{}, err
+	}
 	fileInfo, statErr := os.Stat(filePath)
 	if nil != statErr {
 		return Terraform{}, statErr
 	}
-	if fileInfo.IsDir() {
-		// We know we're dealing with a directory, so we'll just iterate over the files in it
-		files, _ := ioutil.ReadDir(filePath)
-		terraform := Terraform{}
-		noTerraform := true
-		for _, file := range files {
-			if strings.HasSuffix(file.Name(), ".tf") {
-				noTerraform = false
-				childPath := path.Join(filePath, file.Name())
-				childTerraform, childProcessErr := processFile(childPath)
-				if nil != childProcessErr {
-					return Terraform{}, childProcessErr
-				}
-				terraform.Variables = append(terraform.Variables, childTerraform.Variables...)
-				terraform.Outputs = append(terraform.Outputs, childTerraform.Outputs...)
-			}
+	if !fileInfo.IsDir() {
+		return fileParser(filePath, opts)
+	}
+	childPaths, err := terraformFiles(filePath, opts)
+	if nil != err {
+		return Terraform{}, err
+	}
+	if 0 == len(childPaths) {
+		return Terraform{}, fmt.Errorf("no Terraform files found in directory %s", filePath)
+	}
+	terraform := Terraform{}
+	var diagErrs hcl.Diagnostics
+	for _, childPath := range childPaths {
+		if err = ctx.Err(); nil != err {
+			return Terraform{}, err
 		}
-		if noTerraform {
-			return Terraform{}, fmt.Errorf("no Terraform files found in directory %s", filePath)
+		childTerraform, childProcessErr := fileParser(childPath, opts)
+		if nil != childProcessErr {
+			// Whatever the file did contain is still merged below
+			diagErrs = append(diagErrs, asDiagnostics(childProcessErr, childPath)...)
 		}
-		return terraform, nil
+		var mergeDiags hcl.Diagnostics
+		terraform, mergeDiags = mergeTerraform(terraform, childTerraform, &hcl.Range{Filename: childPath})
+		diagErrs = append(diagErrs, mergeDiags...)
 	}
-	return processFile(filePath)
+	var dedupeDiags hcl.Diagnostics
+	terraform.Variables, dedupeDiags = dedupeVariables(terraform.Variables, opts.Dedupe)
+	diagErrs = append(diagErrs, dedupeDiags...)
+	terraform.Sort()
+	if diagErrs.HasErrors() {
+		return terraform, &ParseError{Path: filePath, Diagnostics: diagErrs}
+	}
+	return terraform, nil
+}
+
+// cachedFile is a successfully parsed file along with what it was parsed from
+type cachedFile struct {
+	modTime   time.Time
+	strict    bool
+	terraform Terraform
+}
+
+// Parser parses like ParseWithOptions, but remembers every file it parses successfully and reuses the result until the
+// file's modification time changes. The zero value is ready to use and safe for concurrent use. Results share memory
+// with the cache, so treat them as read-only.
+type Parser struct {
+	// Options configures every parse
+	Options ParseOptions
+	mutex   sync.Mutex
+	cache   map[string]cachedFile
+}
+
+// parseFile parses exactly one Terraform file, reusing the cached result when the file hasn't changed
+func (parser *Parser) parseFile(filePath string, opts ParseOptions) (Terraform, error) {
+	fileInfo, statErr := os.Stat(filePath)
+	if nil != statErr {
+		return parseFile(filePath, opts)
+	}
+	parser.mutex.Lock()
+	cached, ok := parser.cache[filePath]
+	parser.mutex.Unlock()
+	if ok && cached.strict == opts.Strict && cached.modTime.Equal(fileInfo.ModTime()) {
+		return cached.terraform, nil
+	}
+	terraform, err := parseFile(filePath, opts)
+	if nil != err {
+		return terraform, err
+	}
+	parser.mutex.Lock()
+	defer parser.mutex.Unlock()
+	if nil == parser.cache {
+		parser.cache = map[string]cachedFile{}
+	}
+	parser.cache[filePath] = cachedFile{modTime: fileInfo.ModTime(), strict: opts.Strict, terraform: terraform}
+	return terraform, nil
+}
+
+// Parse parses a single Terraform file or every Terraform file in a directory, like ParseWithOptions with the
+// Parser's Options, skipping files that haven't changed since they were last parsed
+func (parser *Parser) Parse(filePath string) (Terraform, error) {
+	return parse(context.Background(), filePath, parser.Options, parser.parseFile)
 }
@@ -15,10 +15,17 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -28,6 +35,28 @@ const (
 	fixtureDirectory = "test_fixtures"
 	// fixtureDirectoryTerraform is the directory with only Terraform files
 	fixtureDirectoryTerraform = "terraform"
+	// fixtureDirectoryComments is a directory with a comment-only file next to a file with content
+	fixtureDirectoryComments = "comments"
+	// fixtureDirectoryDuplicates is a directory whose files both declare the same variable
+	fixtureDirectoryDuplicates = "duplicates"
+	// fixtureDirectoryJsonOnly is a directory whose only Terraform file uses the JSON syntax
+	fixtureDirectoryJsonOnly = "json_only"
+	// fixtureDirectoryLocalsAcrossFiles is a directory whose files define the same local
+	fixtureDirectoryLocalsAcrossFiles = "locals_across_files"
+	// fixtureDirectoryMixedExtensions has one native syntax file and one JSON file
+	fixtureDirectoryMixedExtensions = "mixed_extensions"
+	// fixtureDirectoryNewerBlocks is a directory with one file for each block type added in recent Terraform releases
+	fixtureDirectoryNewerBlocks = "newer_blocks"
+	// fixtureDirectoryPartiallyBroken has two broken files and one good file
+	fixtureDirectoryPartiallyBroken = "partially_broken"
+	// fixtureDirectoryWithOverrides is a directory with a regular file and two override files
+	fixtureDirectoryWithOverrides = "with_overrides"
+	// fixtureDirectoryWithSubmodules is a root module with a nested module under modules/
+	fixtureDirectoryWithSubmodules = "with_submodules"
+	// fixtureFileEquivalentHcl is a native syntax file with a variable and an output
+	fixtureFileEquivalentHcl = "equivalent.tf"
+	// fixtureFileEquivalentJson is the JSON syntax twin of fixtureFileEquivalentHcl
+	fixtureFileEquivalentJson = "equivalent.tf.json"
 	// fixtureFileHclWontParse is a file that will not parse because of a syntax error
 	fixtureFileHclWontParse = "hcl_wont_parse.hcl"
 	// fixtureFileDoesntExist is a file that does not exist (do not create it!)
@@ -36,6 +65,56 @@ const (
 	fixtureFileParseableHcl = "parseable_hcl.hcl"
 	// fixtureFileBadTypeDefault is a file containing a variable whose default does not match its type
 	fixtureFileBadTypes = "bad_types.tf"
+	// fixtureFileUnknownType is a file containing a variable with a default whose type constraint can't be resolved
+	fixtureFileUnknownType = "unknown_type.tf"
+	// fixtureFileBackend is a file configuring an s3 backend with a key
+	fixtureFileBackend = "backend.tf"
+	// fixtureFileComplexType is a file containing a variable with a structural type constraint
+	fixtureFileComplexType = "complex_type.tf"
+	// fixtureFileDefaultKinds is a file containing variables with defaults of every kind
+	fixtureFileDefaultKinds = "default_kinds.tf"
+	// fixtureFileConditionalDefaults is a file containing variables with conditional defaults
+	fixtureFileConditionalDefaults = "conditional_defaults.tf"
+	// fixtureFileDefaultReferencesVariable is a file containing a variable whose default references another variable
+	fixtureFileDefaultReferencesVariable = "default_references_variable.tf"
+	// fixtureFileDuplicateLocals is a file defining the same local in two blocks
+	fixtureFileDuplicateLocals = "duplicate_locals.tf"
+	// fixtureFileEmptyVariable is a file containing a variable with an empty body
+	fixtureFileEmptyVariable = "empty_variable.tf"
+	// fixtureFileInterleaved is a file whose variables and outputs are interleaved
+	fixtureFileInterleaved = "interleaved.tf"
+	// fixtureFileInvalidVariableName is a file containing a variable whose name is not a valid identifier
+	fixtureFileInvalidVariableName = "invalid_variable_name.tf"
+	// fixtureFileLocals is a file containing a locals block
+	fixtureFileLocals = "locals.tf"
+	// fixtureFileMisspelledBlock is a file containing a misspelled block type next to valid blocks
+	fixtureFileMisspelledBlock = "misspelled_block.tf"
+	// fixtureFileModules is a file containing registry, local, and invalid module calls
+	fixtureFileModules = "modules.tf"
+	// fixtureFileMultilineOutput is a file containing an output whose value spans several lines
+	fixtureFileMultilineOutput = "multiline_output.tf"
+	// fixtureFileNestedObjectType is a file containing a variable whose object type nests two levels deep
+	fixtureFileNestedObjectType = "nested_object_type.tf"
+	// fixtureFileOptionalAttributes is a file containing a variable whose object type uses optional attributes
+	fixtureFileOptionalAttributes = "optional_attributes.tf"
+	// fixtureFileNumberDefaults is a file containing number variables with quoted and numeric defaults
+	fixtureFileNumberDefaults = "number_defaults.tf"
+	// fixtureFileStringDefaults is a file containing string variables with number and bool defaults
+	fixtureFileStringDefaults = "string_defaults.tf"
+	// fixtureFileOutputFunctions is a file containing an output whose value calls a function
+	fixtureFileOutputFunctions = "output_functions.tf"
+	// fixtureFileProviderMeta is a file whose terraform block has a provider_meta block
+	fixtureFileProviderMeta = "provider_meta.tf"
+	// fixtureFileSetOfObjects is a file containing a variable whose type is a set of objects
+	fixtureFileSetOfObjects = "set_of_objects.tf"
+	// fixtureFileSensitiveOutputs is a file containing outputs with sensitive set, unset, omitted, and invalid
+	fixtureFileSensitiveOutputs = "sensitive_outputs.tf"
+	// fixtureFileSensitiveVariables is a file containing variables with sensitive set, unset, and omitted
+	fixtureFileSensitiveVariables = "sensitive_variables.tf"
+	// fixtureFileTabIndented is a tab-indented file containing a variable whose default does not match its type
+	fixtureFileTabIndented = "tab_indented.tf"
+	// fixtureFileCommentsOnly is a file containing nothing but comments
+	fixtureFileCommentsOnly = "only_comments.tf"
 	// fixtureFileTerraformOnlyVariables is a file containing only variable declarations
 	fixtureFileTerraformOnlyVariables = "only_variables.tf"
 	// fixtureFileTerraformOnlyOutputs is a file containing only variable declarations
@@ -59,7 +138,7 @@ func TestParserTestSuite(t *testing.T) {
 	suite.Run(t, new(ParserTestSuite))
 }
 
-func (suite *ParserTestSuite) Test_checkDiagnostics_NoAllowedErrors() {
+func (suite *ParserTestSuite) Test_FilterDiagnostics_NoAllowedErrors() {
 	diags := hcl.Diagnostics{
 		{
 			Severity: hcl.DiagError,
@@ -67,11 +146,11 @@ func (suite *ParserTestSuite) Test_checkDiagnostics_NoAllowedErrors() {
 			Detail:   "This is not allowed",
 		},
 	}
-	parsedDiags := checkDiagnostics(diags, nil)
+	parsedDiags := FilterDiagnostics(diags, nil)
 	suite.Equalf(diags, parsedDiags, "Diagnostics should be %v", diags)
 }
 
-func (suite *ParserTestSuite) Test_checkDiagnostics_AllAllowedErrors() {
+func (suite *ParserTestSuite) Test_FilterDiagnostics_AllAllowedErrors() {
 	diags := hcl.Diagnostics{
 		{
 			Severity: hcl.DiagError,
@@ -82,11 +161,11 @@ func (suite *ParserTestSuite) Test_checkDiagnostics_AllAllowedErrors() {
 	allowedErrors := []string{
 		"Allowed diagnostic",
 	}
-	parsedDiags := checkDiagnostics(diags, allowedErrors)
+	parsedDiags := FilterDiagnostics(diags, allowedErrors)
 	suite.Nilf(parsedDiags, "Diagnostics should be nil")
 }
 
-func (suite *ParserTestSuite) Test_checkDiagnostics_MixOfErrors() {
+func (suite *ParserTestSuite) Test_FilterDiagnostics_MixOfErrors() {
 	diags := hcl.Diagnostics{
 		{
 			Severity: hcl.DiagError,
@@ -109,11 +188,11 @@ func (suite *ParserTestSuite) Test_checkDiagnostics_MixOfErrors() {
 			Detail:   "This is not allowed",
 		},
 	}
-	parsedDiags := checkDiagnostics(diags, allowedErrors)
+	parsedDiags := FilterDiagnostics(diags, allowedErrors)
 	suite.Equalf(expectedDiags, parsedDiags, "Diagnostics should be %v", expectedDiags)
 }
 
-func (suite *ParserTestSuite) Test_checkDiagnostics_MultipleAllowedErrors() {
+func (suite *ParserTestSuite) Test_FilterDiagnostics_MultipleAllowedErrors() {
 	diags := hcl.Diagnostics{
 		{
 			Severity: hcl.DiagError,
@@ -130,10 +209,39 @@ func (suite *ParserTestSuite) Test_checkDiagnostics_MultipleAllowedErrors() {
 		"Allowed diagnostic one",
 		"Allowed diagnostic two",
 	}
-	parsedDiags := checkDiagnostics(diags, allowedErrors)
+	parsedDiags := FilterDiagnostics(diags, allowedErrors)
 	suite.Nilf(parsedDiags, "Diagnostics should be nil")
 }
 
+func (suite *ParserTestSuite) Test_FilterDiagnostics_MatchesDetail() {
+	diags := hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported block type",
+			Detail:   `Blocks of type "resource" are not expected here.`,
+		},
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported block type",
+			Detail:   `Blocks of type "data" are not expected here.`,
+		},
+	}
+	parsedDiags := FilterDiagnostics(diags, []string{`TYPE "RESOURCE"`})
+	suite.Equalf(diags[1:], parsedDiags, "Only the diagnostic with a matching detail should be dropped")
+}
+
+func (suite *ParserTestSuite) Test_FilterDiagnostics_MatchesSummary() {
+	diags := hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported block type",
+			Detail:   `Blocks of type "resource" are not expected here.`,
+		},
+	}
+	parsedDiags := FilterDiagnostics(diags, []string{DiagIgnoreUnsupportedBlock})
+	suite.Nilf(parsedDiags, "Diagnostics with a matching summary should be dropped")
+}
+
 func (suite *ParserTestSuite) Test_loadFile_WontParse() {
 	filePath := path.Join(suite.fixtureDirectory, fixtureFileHclWontParse)
 	rawHcl, parseErr := loadFile(filePath)
@@ -164,24 +272,235 @@ func (suite *ParserTestSuite) Test_processSchema_SchemaWithErrors() {
 				Required: true,
 			},
 		},
-	})
+	}, false)
 	suite.Nilf(body, "Body should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
 func (suite *ParserTestSuite) Test_processSchema_SchemaWithoutErrors() {
 	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileParseableHcl))
-	body, diags := processSchema(rawHcl, &hcl.BodySchema{})
+	body, diags := processSchema(rawHcl, &hcl.BodySchema{}, false)
 	suite.NotNilf(body, "Body should not be nil")
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
 
+func (suite *ParserTestSuite) Test_processSchema_LenientIgnoresMisspelledBlocks() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileMisspelledBlock))
+	body, diags := processSchema(rawHcl, importantBlocksSchema, false)
+	suite.Lenf(body.Blocks, 1, "Only the variable should be kept")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processSchema_StrictReportsMisspelledBlocks() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileMisspelledBlock))
+	body, diags := processSchema(rawHcl, importantBlocksSchema, true)
+	suite.Nilf(body, "Body should be nil")
+	suite.Lenf(diags, 1, "Only the misspelled block should be reported")
+	suite.Containsf(diags[0].Detail, `"varaible"`, "Diagnostic should name the misspelled block")
+}
+
+func (suite *ParserTestSuite) Test_processSchema_StrictAcceptsNewerBlocks() {
+	for _, fileName := range []string{"check.tf", "ephemeral.tf", "removed.tf"} {
+		rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureDirectoryNewerBlocks, fileName))
+		body, diags := processSchema(rawHcl, importantBlocksSchema, true)
+		suite.NotNilf(body, "Body should not be nil for %s", fileName)
+		suite.Nilf(diags, "Diagnostics should be nil for %s", fileName)
+	}
+}
+
 func (suite *ParserTestSuite) Test_processVariables_OnlyVariables() {
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	variable, diags := processVariable(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.NotNilf(variable, "Variable should not be nil")
+	suite.Equalf("The first variable", variable.Description, "Description should be captured")
+	suite.Equalf("string", variable.Type, "Type should be captured")
+	suite.Truef(variable.HasDefault, "Variable should have a default")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_NoDefault() {
+	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[2], rawHcl.Bytes)
+	suite.NotNilf(variable, "Variable should not be nil")
+	suite.Falsef(variable.HasDefault, "Variable should not have a default")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_NoDescription() {
+	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[1], rawHcl.Bytes)
+	suite.NotNilf(variable, "Variable should not be nil")
+	suite.Emptyf(variable.Description, "Description should default to empty")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_ComplexType() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileComplexType))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
 	suite.NotNilf(variable, "Variable should not be nil")
+	suite.Equalf("object({\n    name = string\n    tags = map(string)\n  })", variable.Type, "Type should be the raw source")
+	expectedConstraint := cty.Object(map[string]cty.Type{"name": cty.String, "tags": cty.Map(cty.String)})
+	suite.Truef(expectedConstraint.Equals(variable.TypeConstraint), "Type constraint should be resolved")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_SetOfObjects() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileSetOfObjects))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
 	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Require().Truef(variable.TypeConstraint.IsSetType(), "Type constraint should be a set")
+	expectedElement := cty.Object(map[string]cty.Type{"port": cty.Number, "cidr_blocks": cty.List(cty.String)})
+	suite.Truef(expectedElement.Equals(variable.TypeConstraint.ElementType()), "Element type should be the object")
+	expected := []*ObjectAttribute{
+		{Name: "cidr_blocks", Type: "list(string)"},
+		{Name: "port", Type: "number"},
+	}
+	suite.Equalf(expected, variable.ObjectAttributes, "Object attributes should come from the element type")
+	suite.Truef(cty.SetValEmpty(expectedElement).RawEquals(variable.Default), "Default should convert to an empty set")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_OptionalAttributes() {
+	terraform, err := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileOptionalAttributes))
+	suite.Require().Nilf(err, "optional() should parse")
+	suite.Require().Lenf(terraform.Variables, 1, "The variable should be kept")
+	variable := terraform.Variables[0]
+	suite.Truef(variable.TypeConstraint.IsObjectType(), "Type constraint should be resolved")
+	suite.Falsef(variable.TypeConstraint.AttributeOptional("name"), "name should be required")
+	suite.Truef(variable.TypeConstraint.AttributeOptional("port"), "port should be optional")
+	optional := map[string]bool{}
+	for _, attribute := range variable.ObjectAttributes {
+		optional[attribute.Name] = attribute.Optional
+	}
+	suite.Equalf(map[string]bool{"logging": true, "name": false, "port": true, "tags": true}, optional, "Optional attributes should be marked")
+	suite.Equalf("app", variable.Default.GetAttr("name").AsString(), "The default should be decoded")
+	suite.Truef(cty.NumberIntVal(8080).RawEquals(variable.Default.GetAttr("port")), "Optional defaults should be applied")
+	suite.Truef(variable.Default.GetAttr("tags").IsNull(), "Optional attributes without a default should be null")
+	suite.Equalf("info", variable.Default.GetAttr("logging").GetAttr("level").AsString(), "Nested optional defaults should be applied")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_NestedObjectType() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileNestedObjectType))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Containsf(variable.Type, "b = list(object({", "Type should keep the full source")
+	expected := []*ObjectAttribute{
+		{
+			Name: "a",
+			Type: "object({b=list(object({c=string}))})",
+			Attributes: []*ObjectAttribute{
+				{
+					Name: "b",
+					Type: "list(object({c=string}))",
+					Attributes: []*ObjectAttribute{
+						{
+							Name: "c",
+							Type: "string",
+						},
+					},
+				},
+			},
+		},
+	}
+	suite.Equalf(expected, variable.ObjectAttributes, "Object attributes should recurse through nested types")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_NestedObjectTypeTooDeep() {
+	oldMaxObjectAttributeDepth := maxObjectAttributeDepth
+	defer (func() { maxObjectAttributeDepth = oldMaxObjectAttributeDepth })()
+	maxObjectAttributeDepth = 2
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileNestedObjectType))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(variable, "Variable should be nil")
+	suite.NotNilf(diags, "Diagnostics should not be nil")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_Sensitive() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileSensitiveVariables))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	expected := []bool{true, false, false}
+	for index, block := range body.Blocks {
+		variable, diags := processVariable(block, rawHcl.Bytes)
+		suite.NotNilf(variable, "Variable should not be nil")
+		suite.Equalf(expected[index], variable.Sensitive, "Sensitive should be %t for %s", expected[index], variable.Name)
+		suite.Nilf(diags, "Diagnostics should be nil")
+	}
+}
+
+func (suite *ParserTestSuite) Test_processVariables_DefaultKinds() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileDefaultKinds))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	expected := []cty.Value{
+		cty.NumberIntVal(5),
+		cty.True,
+		cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		cty.MapVal(map[string]cty.Value{"key": cty.StringVal("value")}),
+		cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)}),
+	}
+	for index, block := range body.Blocks {
+		variable, diags := processVariable(block, rawHcl.Bytes)
+		suite.NotNilf(variable, "Variable should not be nil")
+		suite.Truef(expected[index].RawEquals(variable.Default), "Default for %s should be %#v", variable.Name, expected[index])
+		suite.Nilf(diags, "Diagnostics should be nil")
+	}
+}
+
+func (suite *ParserTestSuite) Test_processVariables_ConditionalDefaults() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileConditionalDefaults))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	literal, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Truef(cty.NumberIntVal(3).RawEquals(literal.Default), "Literal conditionals should be evaluated")
+	suite.Emptyf(literal.DefaultExpression, "Literal conditionals should not be kept raw")
+	fromLocal, diags := processVariable(body.Blocks[1], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Truef(fromLocal.HasDefault, "Variable should have a default")
+	suite.Falsef(fromLocal.Default.IsKnown(), "The default value should be unknown")
+	suite.Equalf("local.production ? 3 : 1", fromLocal.DefaultExpression, "The default should be kept raw")
+	fromVariable, diags := processVariable(body.Blocks[2], rawHcl.Bytes)
+	suite.Nilf(fromVariable, "Variable should be nil")
+	suite.Lenf(diags, 1, "Referencing a variable should still be reported")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_DefaultReferencesVariable() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileDefaultReferencesVariable))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[1], rawHcl.Bytes)
+	suite.Nilf(variable, "Variable should be nil")
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Equalf("Variable defaults cannot reference other variables.", diags[0].Detail, "Diagnostic should explain the reference")
+	suite.Equalf(8, diags[0].Subject.Start.Line, "Diagnostic should point at the reference")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_QuotedNumberDefault() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileNumberDefaults))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(variable, "Variable should be nil")
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Equalf("This default value is a string but the variable's type constraint is number.", diags[0].Detail, "Diagnostic should explain the mismatch")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_StringDefaults() {
+	terraform, err := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileStringDefaults))
+	suite.Require().Nilf(err, "Error should be nil")
+	suite.Require().Lenf(terraform.Variables, 2, "Every variable should be parsed")
+	suite.Equalf(cty.StringVal("8080"), terraform.Variables[0].Default, "Number defaults should convert to strings")
+	suite.Equalf(cty.StringVal("true"), terraform.Variables[1].Default, "Bool defaults should convert to strings")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_NumericDefault() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileNumberDefaults))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[1], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Truef(cty.NumberIntVal(123).RawEquals(variable.Default), "Default should be a number")
 }
 
 func (suite *ParserTestSuite) Test_processVariables_VariableSchemaFails() {
@@ -196,32 +515,65 @@ func (suite *ParserTestSuite) Test_processVariables_VariableSchemaFails() {
 		},
 	}
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	variable, diags := processVariable(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
 	suite.Nilf(variable, "Variable should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
 func (suite *ParserTestSuite) Test_processVariables_NotAVariable() {
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyOutputs))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	variable, diags := processVariable(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
 	suite.Nilf(variable, "Variable should be nil")
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
 
 func (suite *ParserTestSuite) Test_processVariables_BadType() {
 	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileBadTypes))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	variable, diags := processVariable(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
 	suite.Nilf(variable, "Variable should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
+func (suite *ParserTestSuite) Test_processVariables_UnknownTypeFallsBackToAny() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileUnknownType))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.NotNilf(variable, "Variable should not be dropped")
+	suite.Equalf("strnig", variable.Type, "The raw type should be kept")
+	suite.Truef(cty.DynamicPseudoType.Equals(variable.TypeConstraint), "The type constraint should fall back to any")
+	suite.Truef(variable.HasDefault, "Variable should have a default")
+	suite.Equalf("value", variable.Default.GetAttr("kept").AsString(), "The default should be decoded")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_EmptyBody() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileEmptyVariable))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	variable, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.NotNilf(variable, "Variable should not be nil")
+	suite.Equalf("empty", variable.Name, "Variable name should be captured")
+	suite.Falsef(variable.HasDefault, "Variable should not have a default")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processVariables_TabIndentedRange() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileTabIndented))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	_, diags := processVariable(body.Blocks[0], rawHcl.Bytes)
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	subject := diags[0].Subject
+	suite.Equalf(3, subject.Start.Line, "Diagnostic should start on the default line")
+	suite.Equalf(12, subject.Start.Column, "A tab should count as a single column")
+	suite.Equalf(byte('{'), rawHcl.Bytes[subject.Start.Byte], "Diagnostic should point at the default expression")
+}
+
 func (suite *ParserTestSuite) Test_processOutputs_OnlyOutputs() {
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyOutputs))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	output, diags := processOutput(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[0], rawHcl.Bytes)
 	suite.NotNilf(output, "Output should not be nil")
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
@@ -238,68 +590,357 @@ func (suite *ParserTestSuite) Test_processOutputs_OutputSchemaFails() {
 		},
 	}
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyOutputs))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	output, diags := processOutput(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(output, "Output should be nil")
+	suite.NotNilf(diags, "Diagnostics should not be nil")
+}
+
+func (suite *ParserTestSuite) Test_processOutputs_MultilineValue() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileMultilineOutput))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[0], rawHcl.Bytes)
+	suite.NotNilf(output, "Output should not be nil")
+	expected := "merge(\n    var.base_tags,\n    {\n      Name = \"merged\"\n    },\n  )"
+	suite.Equalf(expected, output.ValueExpression, "Value expression should span every line")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processOutputs_Sensitive() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileSensitiveOutputs))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	expected := []bool{true, false, false}
+	for index, block := range body.Blocks[:len(expected)] {
+		output, diags := processOutput(block, rawHcl.Bytes)
+		suite.NotNilf(output, "Output should not be nil")
+		suite.Equalf(expected[index], output.Sensitive, "Sensitive should be %t for %s", expected[index], output.Name)
+		suite.Nilf(diags, "Diagnostics should be nil")
+	}
+}
+
+func (suite *ParserTestSuite) Test_processOutputs_SensitiveNotABool() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileSensitiveOutputs))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[3], rawHcl.Bytes)
 	suite.Nilf(output, "Output should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
 func (suite *ParserTestSuite) Test_procesOutputs_NotAnOutput() {
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	output, diags := processOutput(body.Blocks[0])
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[0], rawHcl.Bytes)
 	suite.Nilf(output, "Output should be nil")
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
 
-func (suite *ParserTestSuite) Test_processOutputs_BadType() {
+func (suite *ParserTestSuite) Test_processOutputs_ValueIsNotEvaluated() {
 	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileBadTypes))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	output, diags := processOutput(body.Blocks[1])
-	suite.Nilf(output, "Output should be nil")
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[1], rawHcl.Bytes)
+	suite.NotNilf(output, "Output should not be nil")
+	suite.Equalf("{\n    bad = \"value\"\n  }", output.ValueExpression, "Value expression should be the raw source")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processOutputs_FunctionCall() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileOutputFunctions))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	output, diags := processOutput(body.Blocks[0], rawHcl.Bytes)
+	suite.NotNilf(output, "Output should not be nil")
+	suite.Equalf(`try(var.settings.name, "fallback")`, output.ValueExpression, "Value expression should be the raw source")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processModule_Registry() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileModules))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	module, diags := processModule(body.Blocks[0])
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Equalf("vpc", module.Name, "Name should be captured")
+	suite.Equalf("terraform-aws-modules/vpc/aws", module.Source, "Source should be captured")
+	suite.Equalf("3.14.2", module.Version, "Version should be captured")
+}
+
+func (suite *ParserTestSuite) Test_processModule_NoVersion() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileModules))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	module, diags := processModule(body.Blocks[1])
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Equalf("./modules/local", module.Source, "Source should be captured")
+	suite.Emptyf(module.Version, "Version should be empty")
+}
+
+func (suite *ParserTestSuite) Test_processModule_ComputedSource() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileModules))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	module, diags := processModule(body.Blocks[2])
+	suite.Nilf(module, "Module should be nil")
+	suite.NotNilf(diags, "Diagnostics should not be nil")
+}
+
+func (suite *ParserTestSuite) Test_processModule_NotAModule() {
+	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	module, diags := processModule(body.Blocks[0])
+	suite.Nilf(module, "Module should be nil")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processModule_ModuleSchemaFails() {
+	oldModuleBlockSchema := moduleBlockSchema
+	defer (func() { moduleBlockSchema = oldModuleBlockSchema })()
+	moduleBlockSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name:     "missing",
+				Required: true,
+			},
+		},
+	}
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileModules))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	module, diags := processModule(body.Blocks[0])
+	suite.Nilf(module, "Module should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
+func (suite *ParserTestSuite) Test_processLocals_Success() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileLocals))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	locals, diags := processLocals(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Equalf(`"example"`, locals["name"], "Local should be the raw source")
+	suite.Containsf(locals, "tags", "Every local should be captured")
+}
+
+func (suite *ParserTestSuite) Test_processLocals_NotLocals() {
+	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformOnlyVariables))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	locals, diags := processLocals(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(locals, "Locals should be nil")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_processTerraformBlock_ProviderMeta() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileProviderMeta))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	providerMeta, backend, diags := processTerraformBlock(body.Blocks[0], rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Nilf(backend, "Backend should be nil")
+	expected := map[string]map[string]string{
+		"google": {
+			"module_name": `"blueprints/terraform/example/v1.0.0"`,
+		},
+	}
+	suite.Equalf(expected, providerMeta, "provider_meta attributes should be the raw source")
+}
+
+func (suite *ParserTestSuite) Test_processTerraformBlock_NotTerraform() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileProviderMeta))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	providerMeta, backend, diags := processTerraformBlock(body.Blocks[1], rawHcl.Bytes)
+	suite.Nilf(providerMeta, "provider_meta should be nil")
+	suite.Nilf(backend, "Backend should be nil")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_Backend() {
+	terraform, err := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileBackend))
+	suite.Require().Nilf(err, "Error should be nil")
+	suite.Require().NotNilf(terraform.Backend, "Backend should be captured")
+	suite.Equalf("s3", terraform.Backend.Type, "Backend type should be captured")
+	suite.Equalf(cty.StringVal("network/vpc/terraform.tfstate"), terraform.Backend.Config["key"], "Backend config should be evaluated")
+	suite.Equalf(2, terraform.Backend.Position.Line, "Backend position should be captured")
+	key, ok := terraform.SuggestStateKey()
+	suite.Truef(ok, "A key should be suggested")
+	suite.Equalf(`"${path_relative_to_include()}/terraform.tfstate"`, key, "The suggestion should keep the state file name")
+}
+
+func (suite *ParserTestSuite) Test_SuggestStateKey_NoKey() {
+	terraform := Terraform{}
+	_, ok := terraform.SuggestStateKey()
+	suite.Falsef(ok, "Nothing should be suggested without a backend")
+	terraform.Backend = &Backend{Type: "s3", Config: map[string]cty.Value{"bucket": cty.StringVal("state")}}
+	_, ok = terraform.SuggestStateKey()
+	suite.Falsef(ok, "Nothing should be suggested without a key")
+}
+
+func (suite *ParserTestSuite) Test_mergeBackend_Duplicate() {
+	first := &Backend{Type: "s3", Position: Position{Filename: "first.tf", Line: 2, Column: 3}}
+	second := &Backend{Type: "gcs", Position: Position{Filename: "second.tf", Line: 4, Column: 5}}
+	backend, diags := mergeBackend(first, second)
+	suite.Equalf(first, backend, "The first backend should be kept")
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Containsf(diags[0].Detail, "first.tf:2,3", "Diagnostic should point at the original")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_ProviderMeta() {
+	terraform, err := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileProviderMeta))
+	suite.Nilf(err, "Error should be nil")
+	suite.Containsf(terraform.ProviderMeta, "google", "provider_meta should be captured")
+	suite.Lenf(terraform.Variables, 1, "Other blocks should still be parsed")
+}
+
+func (suite *ParserTestSuite) Test_processTerraform_DuplicateLocals() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileDuplicateLocals))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	terraform, diags := processTerraform(body, rawHcl.Bytes)
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Equalf(`"first"`, terraform.Locals["name"], "The first definition should be kept")
+}
+
 func (suite *ParserTestSuite) Test_processTerraform_BadTypes() {
 	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileBadTypes))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	terraform, diags := processTerraform(body)
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	terraform, diags := processTerraform(body, rawHcl.Bytes)
 	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
-	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
+	suite.Lenf(terraform.Outputs, 1, "Terraform outputs are not evaluated so should not fail")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
 func (suite *ParserTestSuite) Test_processTerraform_Success() {
 	rawHcl, _ := loadFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
-	body, _ := processSchema(rawHcl, importantBlocksSchema)
-	terraform, diags := processTerraform(body)
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	terraform, diags := processTerraform(body, rawHcl.Bytes)
 	suite.NotNilf(terraform.Variables, "Terraform variables should not be nil")
 	suite.NotNilf(terraform.Outputs, "Terraform outputs should not be nil")
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
 
-func (suite *ParserTestSuite) Test_processFile_Success() {
-	terraform, diags := processFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
+func (suite *ParserTestSuite) Test_processTerraform_Interleaved() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileInterleaved))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	terraform, diags := processTerraform(body, rawHcl.Bytes)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	var variableNames []string
+	for _, variable := range terraform.Variables {
+		variableNames = append(variableNames, variable.Name)
+	}
+	var outputNames []string
+	for _, output := range terraform.Outputs {
+		outputNames = append(outputNames, output.Name)
+	}
+	suite.Equalf([]string{"first", "second", "third", "fourth"}, variableNames, "Variables should keep source order")
+	suite.Equalf([]string{"alpha", "bravo", "charlie", "delta"}, outputNames, "Outputs should keep source order")
+}
+
+func (suite *ParserTestSuite) Test_processTerraform_InvalidVariableName() {
+	rawHcl, _ := loadFile(path.Join(suite.fixtureDirectory, fixtureFileInvalidVariableName))
+	body, _ := processSchema(rawHcl, importantBlocksSchema, false)
+	terraform, diags := processTerraform(body, rawHcl.Bytes)
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Containsf(diags[0].Detail, `"not-valid"`, "Diagnostic should name the invalid identifier")
+	suite.Lenf(terraform.Variables, 2, "The valid variables should still be parsed")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_SyntaxErrorIsParseError() {
+	filePath := path.Join(suite.fixtureDirectory, fixtureFileHclWontParse)
+	_, err := ParseFile(filePath)
+	var parseErr *ParseError
+	suite.Require().Truef(errors.As(err, &parseErr), "Error should be a ParseError")
+	suite.Equalf(filePath, parseErr.Path, "ParseError should name the file")
+	suite.NotEmptyf(parseErr.Diagnostics, "ParseError should carry the diagnostics")
+	suite.Truef(parseErr.Diagnostics.HasErrors(), "ParseError should carry the errors")
+	var diags hcl.Diagnostics
+	suite.Truef(errors.As(err, &diags), "ParseError should unwrap to diagnostics")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_MissingFileIsNotParseError() {
+	_, err := ParseFile(path.Join(suite.fixtureDirectory, "does_not_exist.tf"))
+	var parseErr *ParseError
+	suite.Falsef(errors.As(err, &parseErr), "Error should not be a ParseError")
+	suite.Truef(errors.Is(err, os.ErrNotExist), "Error should be the read failure")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DirectoryParseError() {
+	directory := path.Join(suite.fixtureDirectory, fixtureDirectoryPartiallyBroken)
+	_, err := Parse(directory)
+	var parseErr *ParseError
+	suite.Require().Truef(errors.As(err, &parseErr), "Error should be a ParseError")
+	suite.Equalf(directory, parseErr.Path, "ParseError should name the directory")
+	suite.Lenf(parseErr.Diagnostics, 2, "ParseError should carry the diagnostics from every file")
+}
+
+// gitRepository commits the files to a new repository and returns its directory, skipping the test without git
+func (suite *ParserTestSuite) gitRepository(files map[string]string) string {
+	if _, err := exec.LookPath("git"); nil != err {
+		suite.T().Skip("git is not installed")
+	}
+	directory := suite.T().TempDir()
+	for name, contents := range files {
+		suite.Require().Nil(os.WriteFile(path.Join(directory, name), []byte(contents), 0644))
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "--all"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "baseline"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", directory}, args...)...).CombinedOutput()
+		suite.Require().Nilf(err, "git %v should succeed: %s", args, output)
+	}
+	return directory
+}
+
+func (suite *ParserTestSuite) Test_ParseGitRef_Success() {
+	directory := suite.gitRepository(map[string]string{"variables.tf": "variable \"committed\" {}\n"})
+	filePath := path.Join(directory, "variables.tf")
+	suite.Require().Nil(os.WriteFile(filePath, []byte("variable \"uncommitted\" {}\n"), 0644))
+	terraform, err := ParseGitRef("HEAD", filePath)
+	suite.Nilf(err, "Error should be nil")
+	suite.Require().Lenf(terraform.Variables, 1, "The committed file should be parsed")
+	suite.Equalf("committed", terraform.Variables[0].Name, "The committed contents should be parsed")
+	suite.Equalf(filePath, terraform.Variables[0].Position.Filename, "Positions should name the file")
+}
+
+func (suite *ParserTestSuite) Test_ParseGitRef_UnknownRef() {
+	directory := suite.gitRepository(map[string]string{"variables.tf": "variable \"committed\" {}\n"})
+	_, err := ParseGitRef("does-not-exist", path.Join(directory, "variables.tf"))
+	suite.NotNilf(err, "Error should not be nil")
+	var exitErr *exec.ExitError
+	suite.Truef(errors.As(err, &exitErr), "Error should wrap the git failure")
+}
+
+func (suite *ParserTestSuite) Test_ParseGitRef_OptionLikeRef() {
+	directory := suite.gitRepository(map[string]string{"variables.tf": "variable \"committed\" {}\n"})
+	written := path.Join(suite.T().TempDir(), "written")
+	_, err := ParseGitRef("--output="+written, path.Join(directory, "variables.tf"))
+	suite.NotNilf(err, "Error should not be nil")
+	_, statErr := os.Stat(written)
+	suite.Truef(errors.Is(statErr, os.ErrNotExist), "git should not have been run with the ref as an option")
+	_, err = ParseGitRef("", path.Join(directory, "variables.tf"))
+	suite.NotNilf(err, "Blank refs should be rejected")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_Success() {
+	terraform, diags := ParseFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
 	suite.NotNilf(terraform.Variables, "Terraform variables should not be nil")
 	suite.NotNilf(terraform.Outputs, "Terraform outputs should not be nil")
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
 
-func (suite *ParserTestSuite) Test_processFile_IsNotHcl() {
-	terraform, diags := processFile("parser_test.go")
+func (suite *ParserTestSuite) Test_ParseFile_OnlyComments() {
+	terraform, diags := ParseFile(path.Join(suite.fixtureDirectory, fixtureDirectoryComments, fixtureFileCommentsOnly))
+	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
+	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_IsNotHcl() {
+	terraform, diags := ParseFile("parser_test.go")
 	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
 	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
-func (suite *ParserTestSuite) Test_processFile_FileDoesNotExist() {
-	terraform, diags := processFile(path.Join(suite.fixtureDirectory, fixtureFileDoesntExist))
+func (suite *ParserTestSuite) Test_ParseFile_FileDoesNotExist() {
+	terraform, diags := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileDoesntExist))
 	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
 	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
-func (suite *ParserTestSuite) Test_processFile_SchemaFails() {
+func (suite *ParserTestSuite) Test_ParseFile_SchemaFails() {
 	oldSchema := importantBlocksSchema
 	defer (func() { importantBlocksSchema = oldSchema })()
 	importantBlocksSchema = &hcl.BodySchema{
@@ -310,13 +951,13 @@ func (suite *ParserTestSuite) Test_processFile_SchemaFails() {
 			},
 		},
 	}
-	terraform, diags := processFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
+	terraform, diags := ParseFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
 	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
 	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
-func (suite *ParserTestSuite) Test_processFile_TerraformFails() {
+func (suite *ParserTestSuite) Test_ParseFile_TerraformFails() {
 	oldSchema := variableBlockSchema
 	defer (func() { variableBlockSchema = oldSchema })()
 	variableBlockSchema = &hcl.BodySchema{
@@ -327,10 +968,74 @@ func (suite *ParserTestSuite) Test_processFile_TerraformFails() {
 			},
 		},
 	}
-	terraform, diags := processFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
+	terraform, diags := ParseFile(path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined))
+	suite.Nilf(terraform.Variables, "Failing variables should be skipped")
+	suite.NotEmptyf(terraform.Outputs, "Outputs should still be parsed")
+	suite.NotNilf(diags, "Diagnostics should not be nil")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_InvalidVariableName() {
+	terraform, err := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileInvalidVariableName))
+	var parseErr *ParseError
+	suite.Require().Truef(errors.As(err, &parseErr), "Error should be a ParseError")
+	suite.Lenf(parseErr.Diagnostics, 1, "Only the invalid name should be reported")
+	suite.Require().Lenf(terraform.Variables, 2, "The valid variables should still be parsed")
+	suite.Equalf("good_one", terraform.Variables[0].Name, "The valid variables should still be parsed")
+	suite.Equalf("good_two", terraform.Variables[1].Name, "The valid variables should still be parsed")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_IsDirectory() {
+	terraform, err := ParseFile(suite.terraformFixtureDirectory)
 	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
 	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
-	suite.NotNilf(diags, "Diagnostics should not be nil")
+	suite.ErrorContainsf(err, "is a directory", "Error should explain the path is a directory")
+}
+
+func (suite *ParserTestSuite) Test_ParseFile_JsonMatchesHcl() {
+	hclTerraform, hclErr := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileEquivalentHcl))
+	jsonTerraform, jsonErr := ParseFile(path.Join(suite.fixtureDirectory, fixtureFileEquivalentJson))
+	suite.Nilf(hclErr, "HCL error should be nil")
+	suite.Nilf(jsonErr, "JSON error should be nil")
+	// Positions necessarily differ between the two files
+	for _, terraform := range []Terraform{hclTerraform, jsonTerraform} {
+		terraform.Variables[0].Position = Position{}
+		terraform.Outputs[0].Position = Position{}
+	}
+	suite.Equalf(hclTerraform, jsonTerraform, "JSON and HCL should produce the same Terraform")
+}
+
+func (suite *ParserTestSuite) Test_ParseReader_Success() {
+	reader := bytes.NewReader([]byte(`
+variable "in_memory" {
+  type    = string
+  default = "in_memory"
+}
+
+output "in_memory" {
+  value = var.in_memory
+}
+`))
+	terraform, err := ParseReader(reader, "in_memory.tf")
+	suite.Nilf(err, "Error should be nil")
+	suite.Lenf(terraform.Variables, 1, "There should be one variable")
+	suite.Lenf(terraform.Outputs, 1, "There should be one output")
+	suite.Equalf("in_memory.tf", terraform.Variables[0].Position.Filename, "Filename should be used for ranges")
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func (suite *ParserTestSuite) Test_ParseReader_ReadFails() {
+	_, err := ParseReader(failingReader{}, "in_memory.tf")
+	suite.NotNilf(err, "Error should not be nil")
+}
+
+func (suite *ParserTestSuite) Test_ParseReader_WontParse() {
+	_, err := ParseReader(bytes.NewReader([]byte("block_doesnt_close {")), "in_memory.tf")
+	suite.NotNilf(err, "Error should not be nil")
 }
 
 func (suite *ParserTestSuite) Test_Parse_DoesNotExist() {
@@ -352,8 +1057,23 @@ func (suite *ParserTestSuite) Test_Parse_DirectoryWithoutTerraform() {
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
 
+func (suite *ParserTestSuite) Test_Parse_SingleFilePositions() {
+	filePath := path.Join(suite.terraformFixtureDirectory, fixtureFileTerraformCombined)
+	terraform, _ := Parse(filePath)
+	suite.Equalf(Position{Filename: filePath, Line: 1, Column: 1}, terraform.Variables[0].Position, "Variable position should match the fixture")
+	suite.Equalf(Position{Filename: filePath, Line: 6, Column: 1}, terraform.Outputs[0].Position, "Output position should match the fixture")
+}
+
 func (suite *ParserTestSuite) Test_Parse_DirectorySuccess() {
 	terraform, diags := Parse(suite.terraformFixtureDirectory)
+	for _, variable := range terraform.Variables {
+		suite.NotEmptyf(variable.Position.Filename, "Variable %s should know its file", variable.Name)
+		suite.NotZerof(variable.Position.Line, "Variable %s should know its line", variable.Name)
+	}
+	for _, output := range terraform.Outputs {
+		suite.NotEmptyf(output.Position.Filename, "Output %s should know its file", output.Name)
+		suite.NotZerof(output.Position.Line, "Output %s should know its line", output.Name)
+	}
 	suite.NotNilf(terraform.Variables, "Terraform variables should not be nil")
 	suite.Greaterf(len(terraform.Variables), 1, "There should be several variables")
 	suite.NotNilf(terraform.Outputs, "Terraform outputs should not be nil")
@@ -361,6 +1081,166 @@ func (suite *ParserTestSuite) Test_Parse_DirectorySuccess() {
 	suite.Nilf(diags, "Diagnostics should be nil")
 }
 
+func (suite *ParserTestSuite) Test_Parse_DirectoryIsSorted() {
+	var firstVariableNames []string
+	var firstOutputNames []string
+	for run := 0; run < 5; run++ {
+		terraform, _ := Parse(suite.terraformFixtureDirectory)
+		var variableNames []string
+		for _, variable := range terraform.Variables {
+			variableNames = append(variableNames, variable.Name)
+		}
+		var outputNames []string
+		for _, output := range terraform.Outputs {
+			outputNames = append(outputNames, output.Name)
+		}
+		if 0 == run {
+			firstVariableNames, firstOutputNames = variableNames, outputNames
+		}
+		suite.Equalf(firstVariableNames, variableNames, "Variable order should be stable across runs")
+		suite.Equalf(firstOutputNames, outputNames, "Output order should be stable across runs")
+	}
+	suite.Equalf([]string{"combined_one", "one", "three", "two"}, firstVariableNames, "Variables should be sorted by name")
+	suite.Equalf([]string{"combined_one", "one", "two"}, firstOutputNames, "Outputs should be sorted by name")
+}
+
+func (suite *ParserTestSuite) Test_Sort_TiesBrokenBySource() {
+	terraform := Terraform{
+		Variables: []*Variable{
+			{Name: "same", Position: Position{Filename: "b.tf", Line: 1, Column: 1}},
+			{Name: "same", Position: Position{Filename: "a.tf", Line: 5, Column: 1}},
+			{Name: "same", Position: Position{Filename: "a.tf", Line: 1, Column: 3}},
+			{Name: "same", Position: Position{Filename: "a.tf", Line: 1, Column: 1}},
+		},
+		Outputs: []*Output{
+			{Name: "same", Position: Position{Filename: "b.tf", Line: 1, Column: 1}},
+			{Name: "same", Position: Position{Filename: "a.tf", Line: 1, Column: 1}},
+			{Name: "first", Position: Position{Filename: "z.tf", Line: 1, Column: 1}},
+		},
+	}
+	terraform.Sort()
+	suite.Equalf(Position{Filename: "a.tf", Line: 1, Column: 1}, terraform.Variables[0].Position, "Ties should sort by file, line, then column")
+	suite.Equalf(Position{Filename: "a.tf", Line: 1, Column: 3}, terraform.Variables[1].Position, "Ties should sort by file, line, then column")
+	suite.Equalf(Position{Filename: "a.tf", Line: 5, Column: 1}, terraform.Variables[2].Position, "Ties should sort by file, line, then column")
+	suite.Equalf(Position{Filename: "b.tf", Line: 1, Column: 1}, terraform.Variables[3].Position, "Ties should sort by file, line, then column")
+	suite.Equalf("first", terraform.Outputs[0].Name, "Outputs should sort by name")
+	suite.Equalf("a.tf", terraform.Outputs[1].Position.Filename, "Output ties should sort by file")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DirectoryWithCommentOnlyFile() {
+	terraform, diags := Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryComments))
+	suite.Lenf(terraform.Variables, 1, "Only the file with content should contribute variables")
+	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DirectoryWithJson() {
+	terraform, diags := Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryJsonOnly))
+	suite.Lenf(terraform.Variables, 1, "The JSON file should be parsed")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DirectoryIgnoresSubmodules() {
+	terraform, diags := Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryWithSubmodules))
+	suite.Lenf(terraform.Variables, 1, "Only the root module should be parsed")
+	suite.Equalf("root", terraform.Variables[0].Name, "Only the root module should be parsed")
+	suite.Nilf(diags, "Diagnostics should be nil")
+}
+
+func (suite *ParserTestSuite) Test_ParseWithOptions_Recursive() {
+	opts := ParseOptions{Recursive: true}
+	terraform, diags := ParseWithOptions(path.Join(suite.fixtureDirectory, fixtureDirectoryWithSubmodules), opts)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Lenf(terraform.Variables, 2, "Nested modules should be parsed")
+	suite.Equalf("child", terraform.Variables[0].Name, "Results should be sorted")
+	suite.Equalf("root", terraform.Variables[1].Name, "Results should be sorted")
+}
+
+func (suite *ParserTestSuite) Test_ParseWithOptions_Extensions() {
+	directory := path.Join(suite.fixtureDirectory, fixtureDirectoryMixedExtensions)
+	terraform, diags := ParseWithOptions(directory, ParseOptions{Extensions: []string{".tf", ".tf.json"}})
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Lenf(terraform.Variables, 2, "Both files should be parsed")
+	suite.Equalf("from_hcl", terraform.Variables[0].Name, "The native syntax file should be parsed")
+	suite.Equalf("from_json", terraform.Variables[1].Name, "The JSON file should be parsed")
+	terraform, diags = ParseWithOptions(directory, ParseOptions{Extensions: []string{".tf"}})
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Lenf(terraform.Variables, 1, "Only the native syntax file should be parsed")
+	suite.Equalf("from_hcl", terraform.Variables[0].Name, "Only the native syntax file should be parsed")
+}
+
+func (suite *ParserTestSuite) Test_ParseWithOptions_EmptyExtensionsUseDefault() {
+	directory := path.Join(suite.fixtureDirectory, fixtureDirectoryMixedExtensions)
+	terraform, diags := ParseWithOptions(directory, ParseOptions{Extensions: []string{}})
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Lenf(terraform.Variables, 2, "Every default extension should be parsed")
+}
+
+func (suite *ParserTestSuite) Test_ParseWithOptions_SkipsOverrides() {
+	terraform, diags := ParseWithOptions(path.Join(suite.fixtureDirectory, fixtureDirectoryWithOverrides), ParseOptions{})
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Lenf(terraform.Variables, 1, "Override files should be skipped")
+	suite.Equalf("region", terraform.Variables[0].Name, "Only the regular file should be parsed")
+}
+
+func (suite *ParserTestSuite) Test_ParseWithOptions_IncludeOverrides() {
+	opts := ParseOptions{IncludeOverrides: true}
+	terraform, diags := ParseWithOptions(path.Join(suite.fixtureDirectory, fixtureDirectoryWithOverrides), opts)
+	suite.Nilf(diags, "Diagnostics should be nil")
+	suite.Lenf(terraform.Variables, 3, "Override files should be parsed")
+	suite.Equalf("from_named_override", terraform.Variables[0].Name, "Named override files should be parsed")
+	suite.Equalf("from_override", terraform.Variables[1].Name, "override.tf should be parsed")
+	suite.Equalf("region", terraform.Variables[2].Name, "The regular file should be parsed")
+}
+
+func (suite *ParserTestSuite) Test_isOverrideFile() {
+	suite.Truef(isOverrideFile("override.tf", ".tf"), "override.tf is an override")
+	suite.Truef(isOverrideFile("override.tf.json", ".tf.json"), "override.tf.json is an override")
+	suite.Truef(isOverrideFile("dns_override.tf", ".tf"), "Suffixed files are overrides")
+	suite.Falsef(isOverrideFile("overrides.tf", ".tf"), "Similar names are not overrides")
+	suite.Falsef(isOverrideFile("override_dns.tf", ".tf"), "Prefixed files are not overrides")
+}
+
+func (suite *ParserTestSuite) Test_ParseContext_Cancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	terraform, err := ParseContext(ctx, suite.terraformFixtureDirectory)
+	suite.Truef(errors.Is(err, context.Canceled), "Error should be the cancellation")
+	suite.Emptyf(terraform.Variables, "Nothing should be parsed")
+}
+
+func (suite *ParserTestSuite) Test_ParseContext_CancelledFile() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParseContext(ctx, path.Join(suite.fixtureDirectory, fixtureFileModules))
+	suite.Truef(errors.Is(err, context.Canceled), "Error should be the cancellation")
+}
+
+func (suite *ParserTestSuite) Test_ParseContext_MatchesParse() {
+	expected, expectedErr := Parse(suite.terraformFixtureDirectory)
+	actual, actualErr := ParseContext(context.Background(), suite.terraformFixtureDirectory)
+	suite.Equalf(expected, actual, "An uncancelled context should parse the same")
+	suite.Equalf(expectedErr, actualErr, "An uncancelled context should fail the same")
+}
+
+func (suite *ParserTestSuite) Test_ParseWithOptions_ZeroValueMatchesParse() {
+	paths := []string{
+		path.Join(suite.fixtureDirectory, fixtureFileModules),
+		path.Join(suite.fixtureDirectory, fixtureFileMisspelledBlock),
+		path.Join(suite.fixtureDirectory, fixtureDirectoryDuplicates),
+		path.Join(suite.fixtureDirectory, fixtureDirectoryPartiallyBroken),
+		path.Join(suite.fixtureDirectory, fixtureDirectoryWithSubmodules),
+		suite.terraformFixtureDirectory,
+		path.Join(suite.fixtureDirectory, "does_not_exist"),
+	}
+	for _, filePath := range paths {
+		expected, expectedErr := Parse(filePath)
+		actual, actualErr := ParseWithOptions(filePath, ParseOptions{})
+		suite.Equalf(expected, actual, "%s should parse the same", filePath)
+		suite.Equalf(expectedErr, actualErr, "%s should fail the same", filePath)
+	}
+}
+
 func (suite *ParserTestSuite) Test_Parse_DirectoryWithDiagErrors() {
 	oldSchema := variableBlockSchema
 	defer (func() { variableBlockSchema = oldSchema })()
@@ -374,6 +1254,172 @@ func (suite *ParserTestSuite) Test_Parse_DirectoryWithDiagErrors() {
 	}
 	terraform, diags := Parse(suite.terraformFixtureDirectory)
 	suite.Nilf(terraform.Variables, "Terraform variables should be nil")
-	suite.Nilf(terraform.Outputs, "Terraform outputs should be nil")
+	suite.NotNilf(terraform.Outputs, "Outputs from files without variables should still be parsed")
 	suite.NotNilf(diags, "Diagnostics should not be nil")
 }
+
+func (suite *ParserTestSuite) Test_Parse_DirectoryCollectsAllDiagnostics() {
+	terraform, err := Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryPartiallyBroken))
+	var diags hcl.Diagnostics
+	suite.Truef(errors.As(err, &diags), "Error should be diagnostics")
+	var filenames []string
+	for _, diag := range diags {
+		filenames = append(filenames, path.Base(diag.Subject.Filename))
+	}
+	suite.Containsf(filenames, "bad_default.tf", "The bad default should be reported")
+	suite.Containsf(filenames, "bad_syntax.tf", "The bad syntax should be reported")
+	suite.Lenf(terraform.Variables, 1, "The good file should still be parsed")
+	suite.Equalf("good", terraform.Variables[0].Name, "The good file should still be parsed")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DuplicatesError() {
+	terraform, err := Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryDuplicates))
+	var diags hcl.Diagnostics
+	suite.Truef(errors.As(err, &diags), "Error should be diagnostics")
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Equalf("second.tf", path.Base(diags[0].Subject.Filename), "Diagnostic should point at the duplicate")
+	suite.Containsf(diags[0].Detail, "first.tf:1,1", "Diagnostic should point at the original")
+	suite.Lenf(terraform.Variables, 2, "Duplicates should be collapsed")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DuplicatesFirstWins() {
+	opts := ParseOptions{Dedupe: DedupeFirstWins}
+	terraform, err := ParseWithOptions(path.Join(suite.fixtureDirectory, fixtureDirectoryDuplicates), opts)
+	suite.Nilf(err, "Error should be nil")
+	suite.Lenf(terraform.Variables, 2, "Duplicates should be collapsed")
+	suite.Equalf("region", terraform.Variables[0].Name, "The first declaration should keep its place")
+	suite.Equalf("first", terraform.Variables[0].Default.AsString(), "The first declaration should win")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DuplicatesLastWins() {
+	opts := ParseOptions{Dedupe: DedupeLastWins}
+	terraform, err := ParseWithOptions(path.Join(suite.fixtureDirectory, fixtureDirectoryDuplicates), opts)
+	suite.Nilf(err, "Error should be nil")
+	suite.Lenf(terraform.Variables, 2, "Duplicates should be collapsed")
+	suite.Equalf("region", terraform.Variables[0].Name, "The first declaration should keep its place")
+	suite.Equalf("second", terraform.Variables[0].Default.AsString(), "The last declaration should win")
+}
+
+func (suite *ParserTestSuite) Test_Parse_DuplicateLocalsAcrossFiles() {
+	terraform, err := Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryLocalsAcrossFiles))
+	var diags hcl.Diagnostics
+	suite.Truef(errors.As(err, &diags), "Error should be diagnostics")
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Equalf("second.tf", path.Base(diags[0].Subject.Filename), "Diagnostic should name the second file")
+	suite.Equalf(map[string]string{"shared": `"first"`, "first": "true", "second": "true"}, terraform.Locals, "Locals should be merged")
+}
+
+func (suite *ParserTestSuite) Test_asDiagnostics_PlainError() {
+	diags := asDiagnostics(errors.New("plain"), "plain.tf")
+	suite.Lenf(diags, 1, "There should be a single diagnostic")
+	suite.Equalf("plain", diags[0].Detail, "Detail should be the error message")
+	suite.Equalf("plain.tf", diags[0].Subject.Filename, "Subject should name the file")
+}
+
+// writeFixture writes a file with a fixed modification time
+func (suite *ParserTestSuite) writeFixture(filePath string, contents string, modTime time.Time) {
+	suite.Require().Nil(os.WriteFile(filePath, []byte(contents), 0644))
+	suite.Require().Nil(os.Chtimes(filePath, modTime, modTime))
+}
+
+func (suite *ParserTestSuite) Test_Parser_UnchangedFileIsNotReread() {
+	filePath := path.Join(suite.T().TempDir(), "variables.tf")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	suite.writeFixture(filePath, "variable \"first\" {}\n", modTime)
+	parser := &Parser{}
+	terraform, err := parser.Parse(filePath)
+	suite.Require().Nilf(err, "Error should be nil")
+	suite.Equalf("first", terraform.Variables[0].Name, "The file should be parsed")
+	// Keeping the modification time means a re-read would be the only way to see the new contents
+	suite.writeFixture(filePath, "variable \"second\" {}\n", modTime)
+	terraform, err = parser.Parse(filePath)
+	suite.Require().Nilf(err, "Error should be nil")
+	suite.Equalf("first", terraform.Variables[0].Name, "The unchanged file should not be re-read")
+}
+
+func (suite *ParserTestSuite) Test_Parser_ChangedFileIsReread() {
+	directory := suite.T().TempDir()
+	filePath := path.Join(directory, "variables.tf")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	suite.writeFixture(filePath, "variable \"first\" {}\n", modTime)
+	parser := &Parser{}
+	_, err := parser.Parse(directory)
+	suite.Require().Nilf(err, "Error should be nil")
+	suite.writeFixture(filePath, "variable \"second\" {}\n", modTime.Add(time.Minute))
+	terraform, err := parser.Parse(directory)
+	suite.Require().Nilf(err, "Error should be nil")
+	suite.Equalf("second", terraform.Variables[0].Name, "The changed file should be re-read")
+}
+
+func (suite *ParserTestSuite) Test_Parser_MatchesParseWithOptions() {
+	opts := ParseOptions{Dedupe: DedupeLastWins}
+	expected, expectedErr := ParseWithOptions(path.Join(suite.fixtureDirectory, fixtureDirectoryDuplicates), opts)
+	parser := &Parser{Options: opts}
+	for attempt := 0; attempt < 2; attempt++ {
+		actual, actualErr := parser.Parse(path.Join(suite.fixtureDirectory, fixtureDirectoryDuplicates))
+		suite.Equalf(expected, actual, "Cached parses should match")
+		suite.Equalf(expectedErr, actualErr, "Cached parses should fail the same")
+	}
+}
+
+func (suite *ParserTestSuite) Test_Merge_NonOverlapping() {
+	first := Terraform{
+		Variables: []*Variable{{Name: "zone"}},
+		Outputs:   []*Output{{Name: "vpc_id"}},
+		Locals:    map[string]string{"first": `"first"`},
+	}
+	second := Terraform{
+		Variables: []*Variable{{Name: "region"}},
+		Outputs:   []*Output{{Name: "subnet_ids"}},
+		Modules:   []*Module{{Name: "network"}},
+		Locals:    map[string]string{"second": `"second"`},
+	}
+	merged := first.Merge(second)
+	suite.Equalf([]*Variable{first.Variables[0], second.Variables[0]}, merged.Variables, "Variables should be concatenated")
+	suite.Equalf([]*Output{first.Outputs[0], second.Outputs[0]}, merged.Outputs, "Outputs should be concatenated")
+	suite.Equalf(second.Modules, merged.Modules, "Modules should be concatenated")
+	suite.Equalf(map[string]string{"first": `"first"`, "second": `"second"`}, merged.Locals, "Locals should be merged")
+	suite.Lenf(first.Variables, 1, "The receiver should not be modified")
+	suite.Lenf(first.Locals, 1, "The receiver should not be modified")
+}
+
+func (suite *ParserTestSuite) Test_Merge_Overlapping() {
+	first := Terraform{Variables: []*Variable{{Name: "region", Description: "first"}}}
+	second := Terraform{Variables: []*Variable{{Name: "region", Description: "second"}}}
+	merged := first.Merge(second)
+	suite.Lenf(merged.Variables, 2, "Every declaration should be kept")
+}
+
+func (suite *ParserTestSuite) Test_MergeWithPolicy_NonOverlapping() {
+	first := Terraform{Variables: []*Variable{{Name: "zone"}}}
+	second := Terraform{Variables: []*Variable{{Name: "region"}}}
+	merged, err := first.MergeWithPolicy(second, DedupeError)
+	suite.Nilf(err, "Error should be nil")
+	suite.Equalf([]*Variable{second.Variables[0], first.Variables[0]}, merged.Variables, "Variables should be merged and sorted")
+}
+
+func (suite *ParserTestSuite) Test_MergeWithPolicy_Overlapping() {
+	first := Terraform{
+		Variables: []*Variable{
+			{Name: "region", Description: "first", Position: Position{Filename: "first.tf", Line: 1, Column: 1}},
+		},
+	}
+	second := Terraform{
+		Variables: []*Variable{
+			{Name: "region", Description: "second", Position: Position{Filename: "second.tf", Line: 1, Column: 1}},
+			{Name: "zone"},
+		},
+	}
+	merged, err := first.MergeWithPolicy(second, DedupeError)
+	var diags hcl.Diagnostics
+	suite.Require().Truef(errors.As(err, &diags), "Error should be diagnostics")
+	suite.Lenf(diags, 1, "The duplicate should be reported")
+	suite.Containsf(diags[0].Detail, "first.tf:1,1", "Diagnostic should point at the original")
+	suite.Lenf(merged.Variables, 2, "Duplicates should be collapsed")
+	merged, err = first.MergeWithPolicy(second, DedupeLastWins)
+	suite.Nilf(err, "Error should be nil")
+	suite.Equalf("second", merged.Variables[0].Description, "The last declaration should win")
+	merged, err = first.MergeWithPolicy(second, DedupeKeepAll)
+	suite.Nilf(err, "Error should be nil")
+	suite.Lenf(merged.Variables, 3, "Every declaration should be kept")
+}
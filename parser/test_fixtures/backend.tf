@@ -0,0 +1,7 @@
+terraform {
+  backend "s3" {
+    bucket = "example-state"
+    key    = "network/vpc/terraform.tfstate"
+    region = "us-east-1"
+  }
+}
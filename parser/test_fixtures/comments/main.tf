@@ -0,0 +1,4 @@
+variable "alongside_comments" {
+  type    = string
+  default = "alongside_comments"
+}
@@ -0,0 +1,7 @@
+# This file intentionally has no blocks.
+// It is still valid HCL.
+/*
+variable "commented_out" {
+  type = string
+}
+*/
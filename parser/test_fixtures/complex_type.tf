@@ -0,0 +1,6 @@
+variable "complex" {
+  type = object({
+    name = string
+    tags = map(string)
+  })
+}
@@ -0,0 +1,25 @@
+variable "number" {
+  type    = number
+  default = 5
+}
+
+variable "bool" {
+  type    = bool
+  default = true
+}
+
+variable "list" {
+  type    = list(string)
+  default = ["a", "b"]
+}
+
+variable "map" {
+  type = map(string)
+  default = {
+    key = "value"
+  }
+}
+
+variable "untyped" {
+  default = ["a", 1]
+}
@@ -0,0 +1,9 @@
+variable "other" {
+  type    = string
+  default = "other"
+}
+
+variable "references" {
+  type    = string
+  default = var.other
+}
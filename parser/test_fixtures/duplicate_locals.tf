@@ -0,0 +1,7 @@
+locals {
+  name = "first"
+}
+
+locals {
+  name = "second"
+}
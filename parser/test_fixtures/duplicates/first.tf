@@ -0,0 +1,8 @@
+variable "region" {
+  type    = string
+  default = "first"
+}
+
+variable "unique" {
+  type = string
+}
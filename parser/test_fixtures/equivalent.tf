@@ -0,0 +1,9 @@
+variable "region" {
+  description = "The region to deploy into"
+  type        = list(string)
+  default     = ["us-east-1"]
+}
+
+output "region" {
+  value = var.region
+}
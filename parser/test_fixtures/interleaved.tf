@@ -0,0 +1,34 @@
+variable "first" {
+  type    = string
+  default = "first"
+}
+
+output "alpha" {
+  value = var.first
+}
+
+output "bravo" {
+  value = var.second
+}
+
+variable "second" {
+  type    = string
+  default = "second"
+}
+
+output "charlie" {
+  value = var.third
+}
+
+variable "third" {
+  type = string
+}
+
+variable "fourth" {
+  type    = string
+  default = "fourth"
+}
+
+output "delta" {
+  value = var.fourth
+}
@@ -0,0 +1,11 @@
+variable "good_one" {
+  type = string
+}
+
+variable "not-valid" {
+  type = string
+}
+
+variable "good_two" {
+  type = string
+}
@@ -0,0 +1,6 @@
+locals {
+  name = "example"
+  tags = merge(var.tags, {
+    Name = local.name
+  })
+}
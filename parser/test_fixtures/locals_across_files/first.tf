@@ -0,0 +1,4 @@
+locals {
+  shared = "first"
+  first  = true
+}
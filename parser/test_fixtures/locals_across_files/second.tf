@@ -0,0 +1,4 @@
+locals {
+  shared = "second"
+  second = true
+}
@@ -0,0 +1,9 @@
+varaible "typo" {
+  type = string
+}
+
+variable "spelled_correctly" {
+  type = string
+}
+
+resource "null_resource" "this" {}
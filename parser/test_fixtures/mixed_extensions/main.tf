@@ -0,0 +1,3 @@
+variable "from_hcl" {
+  type = string
+}
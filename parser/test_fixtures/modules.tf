@@ -0,0 +1,15 @@
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "3.14.2"
+
+  name = "main"
+  cidr = "10.0.0.0/16"
+}
+
+module "local" {
+  source = "./modules/local"
+}
+
+module "computed_source" {
+  source = var.module_source
+}
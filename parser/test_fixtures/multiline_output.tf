@@ -0,0 +1,8 @@
+output "merged" {
+  value = merge(
+    var.base_tags,
+    {
+      Name = "merged"
+    },
+  )
+}
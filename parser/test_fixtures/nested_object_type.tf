@@ -0,0 +1,9 @@
+variable "nested" {
+  type = object({
+    a = object({
+      b = list(object({
+        c = string
+      }))
+    })
+  })
+}
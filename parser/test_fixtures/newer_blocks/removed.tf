@@ -0,0 +1,7 @@
+removed {
+  from = aws_instance.old
+
+  lifecycle {
+    destroy = false
+  }
+}
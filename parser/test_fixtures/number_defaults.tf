@@ -0,0 +1,9 @@
+variable "quoted" {
+  type    = number
+  default = "123"
+}
+
+variable "numeric" {
+  type    = number
+  default = 123
+}
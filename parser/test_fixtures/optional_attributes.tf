@@ -0,0 +1,13 @@
+variable "settings" {
+  type = object({
+    name    = string
+    port    = optional(number, 8080)
+    tags    = optional(map(string))
+    logging = optional(object({
+      level = optional(string, "info")
+    }), {})
+  })
+  default = {
+    name = "app"
+  }
+}
@@ -0,0 +1,3 @@
+output "with_try" {
+  value = try(var.settings.name, "fallback")
+}
@@ -0,0 +1,4 @@
+variable "bad_default" {
+  type    = number
+  default = "not a number"
+}
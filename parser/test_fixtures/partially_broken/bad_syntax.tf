@@ -0,0 +1 @@
+variable "bad_syntax" {
@@ -0,0 +1,4 @@
+variable "good" {
+  type    = string
+  default = "good"
+}
@@ -0,0 +1,17 @@
+terraform {
+  required_version = ">= 1.0"
+
+  required_providers {
+    google = {
+      source = "hashicorp/google"
+    }
+  }
+
+  provider_meta "google" {
+    module_name = "blueprints/terraform/example/v1.0.0"
+  }
+}
+
+variable "project" {
+  type = string
+}
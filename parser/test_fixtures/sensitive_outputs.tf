@@ -0,0 +1,18 @@
+output "sensitive" {
+  value     = "sensitive"
+  sensitive = true
+}
+
+output "not_sensitive" {
+  value     = "not_sensitive"
+  sensitive = false
+}
+
+output "omitted" {
+  value = "omitted"
+}
+
+output "invalid" {
+  value     = "invalid"
+  sensitive = "not a bool"
+}
@@ -0,0 +1,13 @@
+variable "sensitive" {
+  type      = string
+  sensitive = true
+}
+
+variable "not_sensitive" {
+  type      = string
+  sensitive = false
+}
+
+variable "omitted" {
+  type = string
+}
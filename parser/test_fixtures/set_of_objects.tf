@@ -0,0 +1,7 @@
+variable "ingress_rules" {
+  type = set(object({
+    port        = number
+    cidr_blocks = list(string)
+  }))
+  default = []
+}
@@ -0,0 +1,9 @@
+variable "port" {
+  type    = string
+  default = 8080
+}
+
+variable "enabled" {
+  type    = string
+  default = true
+}
@@ -0,0 +1,6 @@
+variable "tabbed" {
+	type = string
+	default = {
+		bad = "value"
+	}
+}
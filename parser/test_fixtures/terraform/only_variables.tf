@@ -1,6 +1,7 @@
 variable "one" {
-  type    = string
-  default = "one"
+  description = "The first variable"
+  type        = string
+  default     = "one"
 }
 
 variable "two" {
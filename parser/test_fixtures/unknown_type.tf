@@ -0,0 +1,6 @@
+variable "unknown_type" {
+  type = strnig
+  default = {
+    kept = "value"
+  }
+}
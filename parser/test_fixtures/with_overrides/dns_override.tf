@@ -0,0 +1,3 @@
+variable "from_named_override" {
+  type = string
+}
@@ -0,0 +1,3 @@
+variable "child" {
+  type = string
+}